@@ -1,102 +1,260 @@
 package blockchain
 
 import (
+	"fast-blocks/blockchain/index"
 	"fast-blocks/blockchain/model"
+	"fast-blocks/blockchain/pow"
 	"fast-blocks/blockchain/stream"
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/wire"
+	"fast-blocks/claims"
+	"fast-blocks/global"
+	"fmt"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-var GenesisHash = chainhash.Hash([chainhash.HashSize]byte{ // Make go vet happy.
-	0x9c, 0x89, 0x28, 0x3b, 0xa0, 0xf3, 0x22, 0x7f,
-	0x6c, 0x03, 0xb7, 0x02, 0x16, 0xb9, 0xf6, 0x65,
-	0xf0, 0x11, 0x8d, 0x5e, 0x0f, 0xa7, 0x29, 0xce,
-	0xdf, 0x4f, 0xb3, 0x4d, 0x6a, 0x34, 0xf4, 0x63,
-})
-
-// MainNetParams define the lbrycrd network. See https://github.com/lbryio/lbrycrd/blob/master/src/chainparams.cpp
-var MainNetParams = chaincfg.Params{
-	PubKeyHashAddrID: 0x55,
-	ScriptHashAddrID: 0x7a,
-	PrivateKeyID:     0x1c,
-	Bech32HRPSegwit:  "lbc",
-	//WitnessPubKeyHashAddrID: , // i cant find these in bitcoin codebase either
-	//WitnessScriptHashAddrID:,
-	GenesisHash:   &GenesisHash,
-	Name:          "mainnet",
-	Net:           wire.BitcoinNet(0xfae4aaf1),
-	DefaultPort:   "9246",
-	BIP0034Height: 1,
-	BIP0065Height: 200000,
-	BIP0066Height: 200000,
-}
-
 type client struct {
 	sync.Mutex
 	blockFilesFound bool
-	blockFilesGiven int
 	blocksDir       string
-	blockFile       string
 	blockFiles      []string
+	loadUndo        bool
+	validateMerkle  bool
+	validatePoW     bool
+	resilient       bool
+	network         *Network
+	channels        *claims.Channels
+	lastHeaders     [2]*model.Block
+	corruptions     []stream.Corruption
+	// undos caches the rev file last read by readBlock
+	undosFile string
+	undos     stream.Undos
 
 	onBlockFn       func(block model.Block)
 	onTransactionFn func(transaction model.Transaction)
 	onInputFn       func(input model.Input)
 	onOutputFn      func(output model.Output)
+	onPurchaseFn    func(tx model.Transaction, purchase model.Output)
+
+	headers *headerGraph
+	index   *index.Index
 }
 
 type Chain interface {
-	NextBlockFile(startingHeight int) (stream.Blocks, error)
+	NextBlockFile() (stream.Blocks, error)
 	OnBlock(func(block model.Block))
 	OnTransaction(func(transaction model.Transaction))
 	OnInput(func(input model.Input))
 	OnOutput(func(output model.Output))
+	OnPurchase(func(tx model.Transaction, purchase model.Output))
 	Notify(block model.Block)
+	Flush() error
+	Tip() (int, bool)
+	BlockAt(height int) (*model.Block, error)
+	Corruptions() []stream.Corruption
 }
 
 type Config struct {
+	// Network is mainnet, testnet or regtest. Defaults to mainnet.
+	Network   string
 	BlocksDir string
-	BlockFile string
+	// IndexDir is lbrycrd's LevelDB block index. Defaults to the index folder inside BlocksDir when it exists.
+	IndexDir string
+	// LoadUndo reads the rev file of every blk file so inputs carry the outputs they spend.
+	LoadUndo bool
+	// ValidateMerkle checks the merkle root and witness commitment of every block while loading.
+	ValidateMerkle bool
+	// ValidatePoW checks the difficulty and proof of work of every block of the best chain as it is delivered.
+	ValidatePoW bool
+	// VerifySignatures checks the channel signature of every signed claim as blocks are delivered.
+	VerifySignatures bool
+	// Resilient skips corrupt regions of blk files instead of failing, see Corruptions.
+	Resilient bool
 }
 
 func New(config Config) (Chain, error) {
-	chain := &client{blocksDir: config.BlocksDir, blockFile: config.BlockFile}
-	err := chain.loadBlockFiles()
+	network, err := GetNetwork(config.Network)
+	if err != nil {
+		return nil, err
+	}
+	global.BlockChainName = network.ChainName
+	chain := &client{
+		network:        network,
+		blocksDir:      config.BlocksDir,
+		loadUndo:       config.LoadUndo,
+		validateMerkle: config.ValidateMerkle,
+		validatePoW:    config.ValidatePoW,
+		resilient:      config.Resilient,
+	}
+	if config.VerifySignatures {
+		chain.channels = claims.NewChannels()
+	}
+	chain.headers = newHeaderGraph(network.Params.GenesisHash.String(), defaultConfirmations, chain.notify, chain.readBlock)
+	err = chain.loadBlockFiles()
+	if err != nil {
+		return nil, err
+	}
+	err = chain.loadIndex(config.IndexDir)
 	if err != nil {
 		return nil, err
 	}
 	return chain, nil
 }
 
-func (c *client) NextBlockFile(startingHeight int) (stream.Blocks, error) {
-	if len(c.blockFiles) == 0 {
-		return nil, nil
+// loadIndex opens the block index set in the config. One found inside the blocks folder is optional, a running
+// lbrycrd holds its lock, so loading continues without it if it cannot be opened.
+func (c *client) loadIndex(dir string) error {
+	detected := dir == ""
+	if detected {
+		dir = filepath.Join(c.blocksDir, "index")
+		if _, err := os.Stat(dir); err != nil {
+			return nil
+		}
+	}
+	idx, err := index.Open(dir)
+	if err != nil {
+		if detected {
+			logrus.Warn("Continuing without the block index ", dir, ": ", err)
+			return nil
+		}
+		return err
+	}
+	c.index = idx
+	if tip, ok := idx.Tip(); ok {
+		logrus.Info("Block index tip: ", tip.Height, " ", tip.Hash)
+	}
+	return nil
+}
+
+// Tip returns the height of the best block according to lbrycrd's block index, if one was loaded.
+func (c *client) Tip() (int, bool) {
+	if c.index == nil {
+		return 0, false
+	}
+	tip, ok := c.index.Tip()
+	if !ok {
+		return 0, false
+	}
+	return tip.Height, true
+}
+
+// BlockAt reads the best chain block at the height directly from its blk file using the block index.
+func (c *client) BlockAt(height int) (*model.Block, error) {
+	if c.index == nil {
+		return nil, errors.Err("no block index loaded")
+	}
+	entry, ok := c.index.AtHeight(height)
+	if !ok || !entry.HasData() {
+		return nil, errors.Err("no block stored at height %d", height)
+	}
+	path := c.blockFilePath(entry.File)
+	blocks, err := stream.NewAt(path, entry.File, entry.DataOffset(), c.streamOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	defer blocks.Close()
+	block, err := blocks.NextBlock()
+	if err != nil {
+		return nil, err
+	}
+	if block.BlockHash != entry.Hash {
+		return nil, errors.Err("expected block %s at height %d but found %s", entry.Hash, height, block.BlockHash)
+	}
+	block.Height = entry.Height
+	return block, nil
+}
+
+// readBlock reads back a block the header graph only kept the header of. It was validated when first read, only its
+// undo record is applied again.
+func (c *client) readBlock(header model.Block) (model.Block, error) {
+	path := c.blockFilePath(header.FileNumber)
+	blocks, err := stream.NewAt(path, header.FileNumber, header.FileOffset, stream.Options{Net: c.network.Params.Net})
+	if err != nil {
+		return model.Block{}, err
+	}
+	defer blocks.Close()
+	block, err := blocks.NextBlock()
+	if err != nil {
+		return model.Block{}, err
+	}
+	if block.BlockHash != header.BlockHash {
+		return model.Block{}, errors.Err("expected block %s at offset %d of %s but found %s", header.BlockHash, header.FileOffset, path, block.BlockHash)
 	}
-	if c.blockFile != "" {
-		for _, file := range c.blockFiles {
-			if file == c.blockFile {
-				c.blockFiles = []string{}
-				return stream.New(file, c.blockFilesGiven, startingHeight, nil)
+	if c.loadUndo {
+		undoFile := c.streamOptions(path).UndoFile
+		if undoFile != c.undosFile {
+			c.undos, err = stream.NewUndos(undoFile)
+			if err != nil {
+				return model.Block{}, err
 			}
+			c.undosFile = undoFile
+		}
+		err = c.undos.Apply(block)
+		if err != nil {
+			return model.Block{}, err
 		}
 	}
+	return *block, nil
+}
+
+func (c *client) blockFilePath(file int) string {
+	return filepath.Join(c.blocksDir, fmt.Sprintf("blk%05d.dat", file))
+}
+
+func (c *client) NextBlockFile() (stream.Blocks, error) {
+	if len(c.blockFiles) == 0 {
+		return nil, nil
+	}
 	c.Lock()
 	defer c.Unlock()
 	next := c.blockFiles[0]
 	c.blockFiles = c.blockFiles[1:]
 	logrus.Info("Starting block file: ", next)
-	c.blockFilesGiven++
-	return stream.New(next, c.blockFilesGiven, startingHeight, nil)
+	return stream.New(next, blockFileNumber(next), nil, c.streamOptions(next))
 }
 
-var blockFileRE = regexp.MustCompile(`.+/blk[0-9]*\.dat`)
+var blockFileRE = regexp.MustCompile(`.+/blk([0-9]*)\.dat`)
+
+// blockFileNumber returns the number of a blk file, the one the block index refers to it by.
+func blockFileNumber(path string) int {
+	match := blockFileRE.FindStringSubmatch(path)
+	if match == nil {
+		return 0
+	}
+	number, _ := strconv.Atoi(match[1])
+	return number
+}
+
+func (c *client) streamOptions(blockFile string) stream.Options {
+	opts := stream.Options{Net: c.network.Params.Net, ValidateMerkle: c.validateMerkle}
+	if c.resilient {
+		opts.OnCorruption = c.onCorruption
+	}
+	if c.loadUndo {
+		dir, name := filepath.Split(blockFile)
+		opts.UndoFile = filepath.Join(dir, "rev"+strings.TrimPrefix(name, "blk"))
+	}
+	return opts
+}
+
+func (c *client) onCorruption(corruption stream.Corruption) {
+	logrus.Warn("Corrupt block data: ", corruption)
+	c.Lock()
+	defer c.Unlock()
+	c.corruptions = append(c.corruptions, corruption)
+}
+
+// Corruptions returns the regions skipped so far in resilient mode.
+func (c *client) Corruptions() []stream.Corruption {
+	c.Lock()
+	defer c.Unlock()
+	return append([]stream.Corruption(nil), c.corruptions...)
+}
 
 func (c *client) loadBlockFiles() error {
 	var files []string
@@ -130,7 +288,35 @@ func (c *client) OnOutput(fn func(model.Output)) {
 	c.onOutputFn = fn
 }
 
+// OnPurchase is called for every purchase output along with its transaction, the other outputs of which pay for the
+// purchased claim. The output's ClaimID is the purchased claim and, with Config.VerifySignatures, its
+// SigningChannelID the channel of the claim.
+func (c *client) OnPurchase(fn func(model.Transaction, model.Output)) {
+	c.onPurchaseFn = fn
+}
+
+// Notify hands a parsed block to the header graph. Blocks reach the registered callbacks once their canonical height
+// on the best chain is known, in height order. Blocks the block index knows to be stale are dropped right away.
 func (c *client) Notify(block model.Block) {
+	if c.index != nil && c.index.IsStale(block.BlockHash) {
+		return
+	}
+	c.headers.add(block)
+}
+
+// Flush delivers the blocks still held back at the tip of the best chain. Call it once all block files are loaded.
+// An error means a block of the best chain could not be read back and no block from its height on was delivered.
+func (c *client) Flush() error {
+	return c.headers.flush()
+}
+
+func (c *client) notify(block model.Block) {
+	if c.validatePoW {
+		c.checkHeader(block)
+	}
+	if c.channels != nil {
+		c.channels.Apply(&block)
+	}
 	if c.onBlockFn != nil {
 		c.onBlockFn(block)
 	}
@@ -138,10 +324,13 @@ func (c *client) Notify(block model.Block) {
 		if c.onTransactionFn != nil {
 			c.onTransactionFn(tx)
 		}
-		if c.onOutputFn != nil {
-			for _, out := range tx.Outputs {
+		for _, out := range tx.Outputs {
+			if c.onOutputFn != nil {
 				c.onOutputFn(out)
 			}
+			if c.onPurchaseFn != nil && out.Purchase != nil {
+				c.onPurchaseFn(tx, out)
+			}
 		}
 		if c.onInputFn != nil {
 			for _, in := range tx.Inputs {
@@ -151,3 +340,14 @@ func (c *client) Notify(block model.Block) {
 	}
 
 }
+
+// checkHeader validates the block against the two blocks delivered before it.
+func (c *client) checkHeader(block model.Block) {
+	err := pow.CheckHeader(&block, c.lastHeaders[0], c.lastHeaders[1], c.network.PoW)
+	if err != nil {
+		logrus.Error("Height ", block.Height, ": ", err)
+	}
+	header := model.Block{BlockHash: block.BlockHash, Header: block.Header, Bits: block.Bits, TimeStamp: block.TimeStamp}
+	c.lastHeaders[1] = c.lastHeaders[0]
+	c.lastHeaders[0] = &header
+}
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"fast-blocks/blockchain/model"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultConfirmations is how deep a block must be buried under the best tip before it is delivered. Blocks of a
+// stale branch shorter than this never get delivered.
+const defaultConfirmations = 100
+
+type headerNode struct {
+	hash     string
+	parent   *headerNode
+	children []*headerNode
+	height   int
+	seen     bool
+	block    *model.Block
+	// headerOnly is set when the transactions of block were dropped, they are read back on delivery
+	headerOnly bool
+}
+
+// headerGraph collects the headers of blocks parsed in any order, links them through their PrevBlockHash starting at
+// the genesis block and delivers the blocks of the best chain in height order. Blocks that cannot be linked yet can
+// wait for many block files to be read, so only their header is kept and load reads them back once delivered.
+type headerGraph struct {
+	sync.Mutex
+	genesis       string
+	confirmations int
+	nodes         map[string]*headerNode
+	tip           *headerNode
+	nextHeight    int
+	deliver       func(block model.Block)
+	load          func(header model.Block) (model.Block, error)
+	// loadErr stops delivery at the block that could not be read back
+	loadErr error
+}
+
+func newHeaderGraph(genesis string, confirmations int, deliver func(block model.Block), load func(header model.Block) (model.Block, error)) *headerGraph {
+	return &headerGraph{
+		genesis:       genesis,
+		confirmations: confirmations,
+		nodes:         make(map[string]*headerNode),
+		deliver:       deliver,
+		load:          load,
+	}
+}
+
+// add links the block into the graph and delivers every block of the best chain that is now deep enough.
+func (g *headerGraph) add(block model.Block) {
+	g.Lock()
+	defer g.Unlock()
+	node := g.node(block.BlockHash)
+	if node.seen {
+		return // lbrycrd can store the same block more than once
+	}
+	node.seen = true
+	node.block = &block
+	if block.BlockHash == g.genesis {
+		g.connect(node, 0)
+	} else {
+		parent := g.node(block.PrevBlockHash)
+		node.parent = parent
+		parent.children = append(parent.children, node)
+		if parent.height >= 0 {
+			g.connect(node, parent.height+1)
+		}
+	}
+	if node.height < 0 && g.load != nil {
+		header := block
+		header.Transactions = nil
+		header.TransactionHashes = nil
+		node.block = &header
+		node.headerOnly = true
+	}
+	g.release(g.confirmations)
+}
+
+// flush delivers the remaining blocks of the best chain up to the tip. It returns the error that stopped delivery
+// when a block could not be read back.
+func (g *headerGraph) flush() error {
+	g.Lock()
+	defer g.Unlock()
+	g.release(0)
+	return g.loadErr
+}
+
+// height returns the canonical height of a block hash if it is connected to the genesis block.
+func (g *headerGraph) height(hash string) (int, bool) {
+	g.Lock()
+	defer g.Unlock()
+	node, ok := g.nodes[hash]
+	if !ok || node.height < 0 {
+		return 0, false
+	}
+	return node.height, true
+}
+
+func (g *headerGraph) node(hash string) *headerNode {
+	node, ok := g.nodes[hash]
+	if !ok {
+		node = &headerNode{hash: hash, height: -1}
+		g.nodes[hash] = node
+	}
+	return node
+}
+
+// connect assigns heights to the node and every descendant that was waiting on it.
+func (g *headerGraph) connect(node *headerNode, height int) {
+	node.height = height
+	pending := []*headerNode{node}
+	for len(pending) > 0 {
+		n := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if g.tip == nil || n.height > g.tip.height {
+			g.tip = n
+		}
+		for _, child := range n.children {
+			child.height = n.height + 1
+			pending = append(pending, child)
+		}
+	}
+}
+
+// release delivers the blocks of the best chain from nextHeight up to depth blocks below the tip. The path is walked
+// once from the tip, so linking the start of a long backlog delivers it in linear time.
+func (g *headerGraph) release(depth int) {
+	if g.loadErr != nil || g.tip == nil || g.nextHeight > g.tip.height-depth {
+		return
+	}
+	var path []*headerNode
+	for node := g.tip; node != nil && node.height >= g.nextHeight; node = node.parent {
+		if node.height <= g.tip.height-depth {
+			path = append(path, node)
+		}
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		node := path[i]
+		block := *node.block
+		if node.headerOnly {
+			var err error
+			block, err = g.load(block)
+			if err != nil {
+				logrus.Error("Stopped delivering blocks at height ", node.height, ": ", err)
+				g.loadErr = err
+				return
+			}
+		}
+		block.Height = node.height
+		node.block = nil
+		g.nextHeight++
+		g.deliver(block)
+	}
+}
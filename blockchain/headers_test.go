@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"fast-blocks/blockchain/model"
+	"strconv"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func TestHeaderGraphOrdersBlocks(t *testing.T) {
+	var delivered []model.Block
+	g := newHeaderGraph("g", 1, func(block model.Block) {
+		delivered = append(delivered, block)
+	}, nil)
+	// b2 and the stale s2 arrive before their parent, the main chain continues at b3.
+	g.add(model.Block{BlockHash: "b2", PrevBlockHash: "b1"})
+	g.add(model.Block{BlockHash: "s2", PrevBlockHash: "b1"})
+	g.add(model.Block{BlockHash: "g"})
+	g.add(model.Block{BlockHash: "b1", PrevBlockHash: "g"})
+	g.add(model.Block{BlockHash: "b1", PrevBlockHash: "g"})
+	g.add(model.Block{BlockHash: "b3", PrevBlockHash: "b2"})
+	g.flush()
+
+	expected := []string{"g", "b1", "b2", "b3"}
+	if len(delivered) != len(expected) {
+		t.Fatalf("expected %d blocks, got %d", len(expected), len(delivered))
+	}
+	for i, block := range delivered {
+		if block.BlockHash != expected[i] || block.Height != i {
+			t.Errorf("expected %s at height %d, got %s at height %d", expected[i], i, block.BlockHash, block.Height)
+		}
+	}
+	if height, ok := g.height("s2"); !ok || height != 2 {
+		t.Errorf("expected stale block at height 2, got %d", height)
+	}
+}
+
+func TestHeaderGraphReleasesBacklog(t *testing.T) {
+	var delivered []model.Block
+	g := newHeaderGraph("0", 0, func(block model.Block) {
+		delivered = append(delivered, block)
+	}, nil)
+	const blocks = 50000
+	for i := 1; i < blocks; i++ {
+		g.add(model.Block{BlockHash: strconv.Itoa(i), PrevBlockHash: strconv.Itoa(i - 1)})
+	}
+	g.add(model.Block{BlockHash: "0"})
+	if len(delivered) != blocks {
+		t.Fatalf("expected %d blocks, got %d", blocks, len(delivered))
+	}
+	for i, block := range delivered {
+		if block.Height != i || block.BlockHash != strconv.Itoa(i) {
+			t.Fatalf("expected block %d at height %d, got %s at height %d", i, i, block.BlockHash, block.Height)
+		}
+	}
+}
+
+func TestHeaderGraphLoadsUnlinkedBlocks(t *testing.T) {
+	var delivered []model.Block
+	var loaded []string
+	g := newHeaderGraph("g", 0, func(block model.Block) {
+		delivered = append(delivered, block)
+	}, func(header model.Block) (model.Block, error) {
+		loaded = append(loaded, header.BlockHash)
+		header.Transactions = []model.Transaction{{Hash: "loaded " + header.BlockHash}}
+		return header, nil
+	})
+	transactions := func(hash string) []model.Transaction {
+		return []model.Transaction{{Hash: "parsed " + hash}}
+	}
+	g.add(model.Block{BlockHash: "b1", PrevBlockHash: "g", Transactions: transactions("b1")})
+	if node := g.nodes["b1"]; node.block.Transactions != nil {
+		t.Error("expected the unlinked block to keep its header only")
+	}
+	g.add(model.Block{BlockHash: "g", Transactions: transactions("g")})
+	g.add(model.Block{BlockHash: "b2", PrevBlockHash: "b1", Transactions: transactions("b2")})
+
+	if len(loaded) != 1 || loaded[0] != "b1" {
+		t.Errorf("expected only b1 to be read back, got %v", loaded)
+	}
+	expected := []string{"parsed g", "loaded b1", "parsed b2"}
+	if len(delivered) != len(expected) {
+		t.Fatalf("expected %d blocks, got %d", len(expected), len(delivered))
+	}
+	for i, block := range delivered {
+		if block.Height != i || block.Transactions[0].Hash != expected[i] {
+			t.Errorf("expected %s at height %d, got %s at height %d", expected[i], i, block.Transactions[0].Hash, block.Height)
+		}
+	}
+}
+
+func TestHeaderGraphReportsLoadError(t *testing.T) {
+	var delivered []model.Block
+	g := newHeaderGraph("g", 0, func(block model.Block) {
+		delivered = append(delivered, block)
+	}, func(header model.Block) (model.Block, error) {
+		return model.Block{}, errors.Err("cannot read %s", header.BlockHash)
+	})
+	g.add(model.Block{BlockHash: "b1", PrevBlockHash: "g"})
+	g.add(model.Block{BlockHash: "g"})
+	g.add(model.Block{BlockHash: "b2", PrevBlockHash: "b1"})
+
+	if err := g.flush(); err == nil {
+		t.Error("expected flush to report that b1 could not be read back")
+	}
+	if len(delivered) != 1 || delivered[0].BlockHash != "g" {
+		t.Errorf("expected delivery to stop before b1, got %v", delivered)
+	}
+}
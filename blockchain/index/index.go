@@ -0,0 +1,227 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/util"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	leveldbutil "github.com/syndtr/goleveldb/leveldb/util"
+	"math/big"
+)
+
+// Status flags of a block index entry. See https://github.com/lbryio/lbrycrd/blob/master/src/chain.h
+const (
+	BlockHaveData    = 8
+	BlockHaveUndo    = 16
+	BlockFailedValid = 32
+	BlockFailedChild = 64
+
+	blockFailedMask = BlockFailedValid | BlockFailedChild
+	headerSize      = 112
+)
+
+var blockIndexPrefix = []byte{'b'}
+
+// Entry is a CDiskBlockIndex record of lbrycrd's blocks/index database.
+type Entry struct {
+	Hash          string
+	PrevBlockHash string
+	Height        int
+	Status        uint32
+	TxCnt         int
+	File          int
+	DataPos       int64
+	UndoPos       int64
+	Header        []byte
+}
+
+// Bits returns the difficulty target of the block in compact form.
+func (e Entry) Bits() uint32 {
+	return binary.LittleEndian.Uint32(e.Header[104:108])
+}
+
+// HasData returns true if the block is stored in a blk file.
+func (e Entry) HasData() bool {
+	return e.Status&BlockHaveData != 0
+}
+
+// HasUndo returns true if the undo data of the block is stored in a rev file.
+func (e Entry) HasUndo() bool {
+	return e.Status&BlockHaveUndo != 0
+}
+
+// Failed returns true if the block or one of its ancestors failed validation.
+func (e Entry) Failed() bool {
+	return e.Status&blockFailedMask != 0
+}
+
+// DataOffset returns the offset of the block's magic number in its blk file. lbrycrd points DataPos past the magic
+// number and block size.
+func (e Entry) DataOffset() int64 {
+	return e.DataPos - 8
+}
+
+// Index holds every block known to lbrycrd and the best chain through them.
+type Index struct {
+	entries map[string]*Entry
+	best    []*Entry
+}
+
+// Open reads the block index stored in dir, usually the blocks/index folder of a lbrycrd data directory.
+func Open(dir string) (*Index, error) {
+	db, err := leveldb.OpenFile(dir, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer db.Close()
+
+	idx := &Index{entries: make(map[string]*Entry)}
+	it := db.NewIterator(leveldbutil.BytesPrefix(blockIndexPrefix), nil)
+	defer it.Release()
+	for it.Next() {
+		entry, err := decodeEntry(it.Key()[1:], it.Value())
+		if err != nil {
+			return nil, err
+		}
+		idx.entries[entry.Hash] = entry
+	}
+	if err := it.Error(); err != nil {
+		return nil, errors.Err(err)
+	}
+	idx.setBestChain()
+	return idx, nil
+}
+
+// Tip returns the last block of the best chain.
+func (i *Index) Tip() (*Entry, bool) {
+	if len(i.best) == 0 {
+		return nil, false
+	}
+	return i.best[len(i.best)-1], true
+}
+
+// AtHeight returns the best chain block at the height. Heights below a gap in the index have none.
+func (i *Index) AtHeight(height int) (*Entry, bool) {
+	if height < 0 || height >= len(i.best) || i.best[height] == nil {
+		return nil, false
+	}
+	return i.best[height], true
+}
+
+// Entry returns the index entry of a block hash.
+func (i *Index) Entry(hash string) (*Entry, bool) {
+	entry, ok := i.entries[hash]
+	return entry, ok
+}
+
+// IsStale returns true if the block is known to the index but not part of the best chain.
+func (i *Index) IsStale(hash string) bool {
+	entry, ok := i.entries[hash]
+	if !ok {
+		return false
+	}
+	best, ok := i.AtHeight(entry.Height)
+	return !ok || best != entry
+}
+
+// setBestChain picks the valid block with data and the most chainwork as the tip, like lbrycrd does, and walks back
+// to the genesis block. A taller branch with less work stays stale.
+func (i *Index) setBestChain() {
+	work := i.chainWork()
+	var tip *Entry
+	for _, entry := range i.entries {
+		if !entry.HasData() || entry.Failed() {
+			continue
+		}
+		if tip == nil {
+			tip = entry
+			continue
+		}
+		if cmp := work[entry].Cmp(work[tip]); cmp > 0 || cmp == 0 && entry.Height > tip.Height {
+			tip = entry
+		}
+	}
+	if tip == nil {
+		return
+	}
+	i.best = make([]*Entry, tip.Height+1)
+	for entry := tip; entry != nil; entry = i.entries[entry.PrevBlockHash] {
+		if entry.Height < 0 || entry.Height >= len(i.best) {
+			break
+		}
+		i.best[entry.Height] = entry
+		if entry.Height == 0 {
+			break
+		}
+	}
+}
+
+// chainWork sums the work of every entry and its ancestors known to the index.
+func (i *Index) chainWork() map[*Entry]*big.Int {
+	work := make(map[*Entry]*big.Int, len(i.entries))
+	for _, entry := range i.entries {
+		var path []*Entry
+		for e := entry; e != nil && work[e] == nil; e = i.entries[e.PrevBlockHash] {
+			path = append(path, e)
+		}
+		for n := len(path) - 1; n >= 0; n-- {
+			w := blockchain.CalcWork(path[n].Bits())
+			if parent, ok := i.entries[path[n].PrevBlockHash]; ok {
+				w.Add(w, work[parent])
+			}
+			work[path[n]] = w
+		}
+	}
+	return work
+}
+
+func decodeEntry(key, value []byte) (*Entry, error) {
+	r := bytes.NewReader(value)
+	entry := &Entry{Hash: hex.EncodeToString(util.ReverseBytes(key))}
+	var fields [4]uint64
+	for n := range fields { // client version, height, status, tx count
+		v, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		fields[n] = v
+	}
+	entry.Height = int(fields[1])
+	entry.Status = uint32(fields[2])
+	entry.TxCnt = int(fields[3])
+	if entry.Status&(BlockHaveData|BlockHaveUndo) != 0 {
+		file, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		entry.File = int(file)
+	}
+	if entry.HasData() {
+		pos, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		entry.DataPos = int64(pos)
+	}
+	if entry.HasUndo() {
+		pos, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		entry.UndoPos = int64(pos)
+	}
+	if r.Len() != headerSize {
+		return nil, errors.Err("block index entry %s has %d header bytes, expected %d", entry.Hash, r.Len(), headerSize)
+	}
+	entry.Header = append([]byte(nil), value[len(value)-headerSize:]...)
+	entry.PrevBlockHash = hex.EncodeToString(util.ReverseBytes(entry.Header[4:36]))
+	if hash := chainhash.DoubleHashH(entry.Header); !bytes.Equal(hash[:], key) {
+		return nil, errors.Err("block index entry %s does not match its header hash %s", entry.Hash, hash.String())
+	}
+	return entry, nil
+}
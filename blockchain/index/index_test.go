@@ -0,0 +1,149 @@
+package index
+
+import (
+	"encoding/binary"
+	"fast-blocks/util"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func header(prev chainhash.Hash, nonce uint32) []byte {
+	h := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(h[0:4], 1)
+	copy(h[4:36], prev[:])
+	binary.LittleEndian.PutUint32(h[108:112], nonce)
+	return h
+}
+
+func putEntry(t *testing.T, db *leveldb.DB, height, status, file, dataPos, undoPos uint64, h []byte) chainhash.Hash {
+	var value []byte
+	for _, v := range []uint64{180000, height, status, 1} {
+		value = append(value, util.WriteVarInt(v)...)
+	}
+	if status&(BlockHaveData|BlockHaveUndo) != 0 {
+		value = append(value, util.WriteVarInt(file)...)
+	}
+	if status&BlockHaveData != 0 {
+		value = append(value, util.WriteVarInt(dataPos)...)
+	}
+	if status&BlockHaveUndo != 0 {
+		value = append(value, util.WriteVarInt(undoPos)...)
+	}
+	value = append(value, h...)
+	hash := chainhash.DoubleHashH(h)
+	err := db.Put(append([]byte{'b'}, hash[:]...), value, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const valid = 3 | BlockHaveData | BlockHaveUndo
+	genesis := putEntry(t, db, 0, valid, 0, 8, 8, header(chainhash.Hash{}, 0))
+	first := putEntry(t, db, 1, valid, 0, 300, 100, header(genesis, 1))
+	stale := putEntry(t, db, 1, BlockHaveData, 1, 70000, 0, header(genesis, 2))
+	second := putEntry(t, db, 2, valid, 1, 1000000, 40, header(first, 3))
+	failed := putEntry(t, db, 3, BlockHaveData|BlockFailedValid, 1, 2000000, 0, header(second, 4))
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, ok := idx.Tip()
+	if !ok || tip.Hash != second.String() || tip.Height != 2 {
+		t.Fatalf("expected tip %s at height 2, got %+v", second, tip)
+	}
+	entry, ok := idx.AtHeight(1)
+	if !ok || entry.Hash != first.String() || entry.PrevBlockHash != genesis.String() {
+		t.Errorf("expected %s at height 1, got %+v", first, entry)
+	}
+	if entry.File != 0 || entry.DataPos != 300 || entry.UndoPos != 100 || entry.DataOffset() != 292 {
+		t.Errorf("unexpected positions %+v", entry)
+	}
+	entry, _ = idx.AtHeight(2)
+	if entry.File != 1 || entry.DataPos != 1000000 || entry.UndoPos != 40 {
+		t.Errorf("unexpected positions %+v", entry)
+	}
+	if !idx.IsStale(stale.String()) || !idx.IsStale(failed.String()) || idx.IsStale(first.String()) {
+		t.Error("stale blocks not detected")
+	}
+	if idx.IsStale("unknown") {
+		t.Error("blocks missing from the index should not be stale")
+	}
+}
+
+func TestOpenWithGap(t *testing.T) {
+	dir := t.TempDir()
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const valid = 3 | BlockHaveData | BlockHaveUndo
+	putEntry(t, db, 0, valid, 0, 8, 8, header(chainhash.Hash{}, 0))
+	// the parent of the tip at height 1 is missing from the index
+	putEntry(t, db, 2, valid, 0, 300, 100, header(chainhash.Hash{1}, 2))
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.AtHeight(2); !ok {
+		t.Error("expected the tip at height 2")
+	}
+	for _, height := range []int{0, 1} {
+		if entry, ok := idx.AtHeight(height); ok {
+			t.Errorf("expected no block at height %d below the gap, got %+v", height, entry)
+		}
+	}
+}
+
+func TestOpenByChainWork(t *testing.T) {
+	dir := t.TempDir()
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withBits := func(h []byte, bits uint32) []byte {
+		binary.LittleEndian.PutUint32(h[104:108], bits)
+		return h
+	}
+	const valid = 3 | BlockHaveData | BlockHaveUndo
+	genesis := putEntry(t, db, 0, valid, 0, 8, 8, withBits(header(chainhash.Hash{}, 0), 0x207fffff))
+	// the taller branch has less work than the single block mined at a higher difficulty
+	parent := genesis
+	for height := uint64(1); height <= 3; height++ {
+		parent = putEntry(t, db, height, valid, 0, 100*height, 0, withBits(header(parent, uint32(height)), 0x207fffff))
+	}
+	best := putEntry(t, db, 1, valid, 1, 8, 0, withBits(header(genesis, 10), 0x1f00ffff))
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tip, ok := idx.Tip(); !ok || tip.Hash != best.String() {
+		t.Fatalf("expected the tip with the most work %s, got %+v", best, tip)
+	}
+	if !idx.IsStale(parent.String()) {
+		t.Error("expected the taller branch to be stale")
+	}
+}
@@ -12,6 +12,7 @@ type Block struct {
 	TimeStamp         time.Time
 	Height            int
 	FileNumber        int
+	FileOffset        int64
 	BlockHash         string
 	PrevBlockHash     string
 	MerkleRoot        string
@@ -9,4 +9,8 @@ type Input struct {
 	Position        uint32
 	Script          *script.Hex
 	Sequence        uint32
+	// Prevout is the output spent by the input, set when the block's undo data is loaded.
+	Prevout *Output
+	// PrevoutHeight is the height the spent output was created at, 0 when lbrycrd did not record it.
+	PrevoutHeight int
 }
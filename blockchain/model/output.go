@@ -5,10 +5,30 @@ import pb "github.com/lbryio/types/v2/go"
 type Output struct {
 	BlockHash       string
 	TransactionHash string
+	Vout            uint32
 	Amount          uint64
 	Address         Address
 	ScriptType      string
 	PKScript        []byte
-	Claim           *pb.Claim
-	Purchase        *pb.Purchase
+	// ClaimOperation is the claim, update or support of a claim script, see the lbrycrd.ClaimOp constants. The
+	// address and InnerScriptType come from the public key script wrapped by it.
+	ClaimOperation  string
+	InnerScriptType string
+	// ClaimName and ClaimID are set for every claim operation, ClaimValue for claims, updates and supports with
+	// data. Purchases set ClaimID to the purchased claim.
+	ClaimName  string
+	ClaimID    string
+	ClaimValue []byte
+	// Claim is the decoded ClaimValue migrated to the current schema, nil if the value does not decode. ClaimFormat
+	// is the format it was published in, see the lbrycrd.ClaimFormat constants.
+	Claim       *pb.Claim
+	ClaimFormat string
+	// SigningChannelID is the channel that signed the claim, IsSignatureValid is set once the chain verified the
+	// signature against the channel's public key. Purchases set SigningChannelID to the channel validly signing the
+	// purchased claim when the chain verifies signatures.
+	SigningChannelID string
+	IsSignatureValid bool
+	// Support is the decoded ClaimValue of a support with data.
+	Support  *pb.Support
+	Purchase *pb.Purchase
 }
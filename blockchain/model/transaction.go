@@ -3,16 +3,17 @@ package model
 import "time"
 
 type Transaction struct {
-	BlockHash string
-	Hash      string
-	Version   uint32
-	IsSegWit  bool
-	InputCnt  uint64
-	Inputs    []Input
-	OutputCnt uint64
-	Outputs   []Output
-	Witnesses []Witness
-	LockTime  time.Time
+	BlockHash   string
+	Hash        string
+	WitnessHash string
+	Version     uint32
+	IsSegWit    bool
+	InputCnt    uint64
+	Inputs      []Input
+	OutputCnt   uint64
+	Outputs     []Output
+	Witnesses   []Witness
+	LockTime    time.Time
 }
 
 type Witness struct {
@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"fast-blocks/blockchain/pow"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// Network bundles the parameters that differ between the lbrycrd networks. See https://github.com/lbryio/lbrycrd/blob/master/src/chainparams.cpp
+type Network struct {
+	// ChainName is the name lbrycrd and the lbrycrd package use for the network.
+	ChainName string
+	Params    chaincfg.Params
+	PoW       pow.Params
+}
+
+var GenesisHash = mustHash("9c89283ba0f3227f6c03b70216b9f665f0118d5e0fa729cedf4fb34d6a34f463")
+
+var RegTestGenesisHash = mustHash("6e3fcf1299d4ec5d79c3a4c91d624a4acf9e2e173d95a1a0504f677669687556")
+
+// MainNetParams define the lbrycrd network.
+var MainNetParams = chaincfg.Params{
+	PubKeyHashAddrID: 0x55,
+	ScriptHashAddrID: 0x7a,
+	PrivateKeyID:     0x1c,
+	Bech32HRPSegwit:  "lbc",
+	//WitnessPubKeyHashAddrID: , // i cant find these in bitcoin codebase either
+	//WitnessScriptHashAddrID:,
+	GenesisHash:   &GenesisHash,
+	Name:          "mainnet",
+	Net:           wire.BitcoinNet(0xf1aae4fa),
+	DefaultPort:   "9246",
+	BIP0034Height: 1,
+	BIP0065Height: 200000,
+	BIP0066Height: 200000,
+}
+
+// TestNetParams define the lbrycrd test network. It shares its genesis block with the main network.
+var TestNetParams = chaincfg.Params{
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xef,
+	Bech32HRPSegwit:  "tlbc",
+	GenesisHash:      &GenesisHash,
+	Name:             "testnet",
+	Net:              wire.BitcoinNet(0xe1aae4fa),
+	DefaultPort:      "19246",
+	BIP0034Height:    1,
+	BIP0065Height:    1200,
+	BIP0066Height:    1200,
+}
+
+// RegTestParams define the lbrycrd regression test network.
+var RegTestParams = chaincfg.Params{
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xef,
+	Bech32HRPSegwit:  "rlbc",
+	GenesisHash:      &RegTestGenesisHash,
+	Name:             "regtest",
+	Net:              wire.BitcoinNet(0xd1aae4fa),
+	DefaultPort:      "29246",
+	BIP0034Height:    100000000,
+	BIP0065Height:    1351,
+	BIP0066Height:    1251,
+}
+
+var networks = map[string]*Network{
+	MainNetParams.Name: {ChainName: "lbrycrd_main", Params: MainNetParams, PoW: pow.MainNetParams},
+	TestNetParams.Name: {ChainName: "lbrycrd_testnet", Params: TestNetParams, PoW: pow.TestNetParams},
+	RegTestParams.Name: {ChainName: "lbrycrd_regtest", Params: RegTestParams, PoW: pow.RegTestParams},
+}
+
+// GetNetwork returns the network of the name: mainnet, testnet or regtest. An empty name is mainnet.
+func GetNetwork(name string) (*Network, error) {
+	if name == "" {
+		name = MainNetParams.Name
+	}
+	network, ok := networks[name]
+	if !ok {
+		return nil, errors.Err("unknown network %s, expected mainnet, testnet or regtest", name)
+	}
+	return network, nil
+}
+
+func mustHash(hash string) chainhash.Hash {
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		panic(err)
+	}
+	return *h
+}
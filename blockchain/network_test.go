@@ -0,0 +1,35 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+func TestGetNetwork(t *testing.T) {
+	network, err := GetNetwork("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.Params.GenesisHash.String() != "9c89283ba0f3227f6c03b70216b9f665f0118d5e0fa729cedf4fb34d6a34f463" {
+		t.Errorf("unexpected mainnet genesis %s", network.Params.GenesisHash)
+	}
+	if network.Params.PrivateKeyID != 0x1c {
+		t.Errorf("unexpected mainnet private key prefix %#x", network.Params.PrivateKeyID)
+	}
+	network, err = GetNetwork("testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.Params.PubKeyHashAddrID != 0x6f || network.Params.ScriptHashAddrID != 0xc4 || network.Params.PrivateKeyID != 0xef {
+		t.Errorf("unexpected testnet prefixes %+v", network.Params)
+	}
+	network, err = GetNetwork("regtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.ChainName != "lbrycrd_regtest" || network.Params.Bech32HRPSegwit != "rlbc" || network.Params.PrivateKeyID != 0xef || !network.PoW.NoRetargeting {
+		t.Errorf("unexpected regtest network %+v", network)
+	}
+	if _, err = GetNetwork("simnet"); err == nil {
+		t.Error("expected unknown network to fail")
+	}
+}
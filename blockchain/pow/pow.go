@@ -0,0 +1,125 @@
+package pow
+
+import (
+	"crypto/sha512"
+	"fast-blocks/blockchain/model"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"golang.org/x/crypto/ripemd160"
+	"math/big"
+)
+
+// Params are the proof of work rules of a lbrycrd network. See https://github.com/lbryio/lbrycrd/blob/master/src/chainparams.cpp
+type Params struct {
+	PowLimit       *big.Int
+	PowLimitBits   uint32
+	TargetTimespan int64
+	TargetSpacing  int64
+	// AllowMinDifficultyBlocks lets a block use the pow limit when it comes more than two spacings after its parent.
+	AllowMinDifficultyBlocks bool
+	NoRetargeting            bool
+}
+
+var MainNetParams = Params{
+	PowLimit:       blockchain.CompactToBig(0x1f00ffff),
+	PowLimitBits:   0x1f00ffff,
+	TargetTimespan: 150,
+	TargetSpacing:  150,
+}
+
+var TestNetParams = Params{
+	PowLimit:                 blockchain.CompactToBig(0x1f00ffff),
+	PowLimitBits:             0x1f00ffff,
+	TargetTimespan:           150,
+	TargetSpacing:            150,
+	AllowMinDifficultyBlocks: true,
+}
+
+var RegTestParams = Params{
+	PowLimit:                 blockchain.CompactToBig(0x207fffff),
+	PowLimitBits:             0x207fffff,
+	TargetTimespan:           150,
+	TargetSpacing:            150,
+	AllowMinDifficultyBlocks: true,
+	NoRetargeting:            true,
+}
+
+// Hash is LBRY's proof of work hash of a block header: sha512 over the double sha256 of the header, then the double
+// sha256 over the ripemd160 of both halves.
+func Hash(header []byte) chainhash.Hash {
+	h := chainhash.DoubleHashH(header)
+	h512 := sha512.Sum512(h[:])
+	left := ripemd160.New()
+	left.Write(h512[:32])
+	right := ripemd160.New()
+	right.Write(h512[32:])
+	return chainhash.DoubleHashH(append(left.Sum(nil), right.Sum(nil)...))
+}
+
+// Target decodes the compact difficulty of a header.
+func Target(bits uint32) *big.Int {
+	return blockchain.CompactToBig(bits)
+}
+
+// CheckProofOfWork verifies that the header hashes below the target encoded in its bits.
+func CheckProofOfWork(header []byte, bits uint32, params Params) error {
+	target := Target(bits)
+	if target.Sign() <= 0 || target.Cmp(params.PowLimit) > 0 {
+		return errors.Err("target %064x of bits %08x is out of range", target, bits)
+	}
+	hash := Hash(header)
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return errors.Err("proof of work hash %s is above the target %064x", hash, target)
+	}
+	return nil
+}
+
+// NextWorkRequired returns the bits expected for the block following last. LBRY retargets every block on the
+// timespan between last and its parent first, damped by 1/8 and clamped. See https://github.com/lbryio/lbrycrd/blob/master/src/pow.cpp
+func NextWorkRequired(block, last, first *model.Block, params Params) uint32 {
+	if last == nil {
+		return params.PowLimitBits
+	}
+	if params.AllowMinDifficultyBlocks && block.TimeStamp.Unix() > last.TimeStamp.Unix()+params.TargetSpacing*2 {
+		return params.PowLimitBits
+	}
+	if params.NoRetargeting {
+		return last.Bits
+	}
+	if first == nil {
+		first = last
+	}
+
+	timespan := params.TargetTimespan
+	actual := last.TimeStamp.Unix() - first.TimeStamp.Unix()
+	modulated := timespan + (actual-timespan)/8
+	minTimespan := timespan - timespan/8
+	maxTimespan := timespan + timespan/2
+	if modulated < minTimespan {
+		modulated = minTimespan
+	} else if modulated > maxTimespan {
+		modulated = maxTimespan
+	}
+
+	target := Target(last.Bits)
+	target.Mul(target, big.NewInt(modulated))
+	target.Div(target, big.NewInt(timespan))
+	if target.Cmp(params.PowLimit) > 0 {
+		target.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(target)
+}
+
+// CheckHeader verifies the difficulty and proof of work of a block given its parent last and grandparent first.
+func CheckHeader(block, last, first *model.Block, params Params) error {
+	expected := NextWorkRequired(block, last, first, params)
+	if block.Bits != expected {
+		return errors.Err("block %s has bits %08x, expected %08x", block.BlockHash, block.Bits, expected)
+	}
+	err := CheckProofOfWork(block.Header, block.Bits, params)
+	if err != nil {
+		return errors.Prefix("block "+block.BlockHash, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package pow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/util"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+)
+
+// genesisHeader is the header of the lbrycrd mainnet genesis block
+func genesisHeader() []byte {
+	header := make([]byte, 112)
+	binary.LittleEndian.PutUint32(header[0:4], 1)
+	merkleRoot, _ := hex.DecodeString("b8211c82c3d15bcd78bba57005b86fed515149a53a425eb592c07af99fe559cc")
+	copy(header[36:68], util.ReverseBytes(merkleRoot))
+	header[68] = 1
+	binary.LittleEndian.PutUint32(header[100:104], 1446058291)
+	binary.LittleEndian.PutUint32(header[104:108], 0x1f00ffff)
+	binary.LittleEndian.PutUint32(header[108:112], 1287)
+	return header
+}
+
+func TestGenesisProofOfWork(t *testing.T) {
+	header := genesisHeader()
+	err := CheckProofOfWork(header, 0x1f00ffff, MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := &model.Block{Header: header, Bits: 0x1f00ffff, TimeStamp: time.Unix(1446058291, 0)}
+	err = CheckHeader(genesis, nil, nil, MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.LittleEndian.PutUint32(header[108:112], 1288)
+	if CheckProofOfWork(header, 0x1f00ffff, MainNetParams) == nil {
+		t.Error("expected a different nonce to fail the proof of work")
+	}
+	if CheckProofOfWork(genesisHeader(), 0x2000ffff, MainNetParams) == nil {
+		t.Error("expected bits above the pow limit to fail")
+	}
+}
+
+func TestNextWorkRequired(t *testing.T) {
+	const bits = 0x1b0bd1de
+	base := time.Unix(1600000000, 0)
+	first := &model.Block{Bits: bits, TimeStamp: base}
+	target := blockchain.CompactToBig(bits)
+	cases := []struct {
+		spacing  int64
+		timespan int64
+	}{
+		{150, 150},  // on target
+		{0, 132},    // fast blocks are clamped to 7/8
+		{30, 135},   // damped by 1/8
+		{1000, 225}, // slow blocks are clamped to 3/2
+		{310, 170},
+	}
+	for _, c := range cases {
+		last := &model.Block{Bits: bits, TimeStamp: base.Add(time.Duration(c.spacing) * time.Second)}
+		expected := new(big.Int).Mul(target, big.NewInt(c.timespan))
+		expected.Div(expected, big.NewInt(150))
+		if next := NextWorkRequired(&model.Block{TimeStamp: last.TimeStamp}, last, first, MainNetParams); next != blockchain.BigToCompact(expected) {
+			t.Errorf("spacing %d: expected %08x, got %08x", c.spacing, blockchain.BigToCompact(expected), next)
+		}
+	}
+}
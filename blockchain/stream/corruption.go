@@ -0,0 +1,35 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+)
+
+// Corruption is a region of a blk file the stream skipped because it could not be read as a block.
+type Corruption struct {
+	File    string
+	Offset  int64
+	Skipped int64
+	Reason  string
+}
+
+func (c Corruption) String() string {
+	return fmt.Sprintf("%s: skipped %d bytes at offset %d: %s", c.File, c.Skipped, c.Offset, c.Reason)
+}
+
+// startResync remembers why the block at start failed and moves past its magic number, so the next read scans for
+// the following one.
+func (bs *blockStream) startResync(start int64, cause error) error {
+	bs.resync = &Corruption{File: bs.path, Offset: start, Reason: cause.Error()}
+	bs.blockEnd = 0
+	_, err := bs.Seek(start+1, io.SeekStart)
+	return err
+}
+
+// finishResync reports the corrupt region once the stream is back in sync at end.
+func (bs *blockStream) finishResync(end int64) {
+	corruption := *bs.resync
+	corruption.Skipped = end - corruption.Offset
+	bs.resync = nil
+	bs.opts.OnCorruption(corruption)
+}
@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func TestResilientStream(t *testing.T) {
+	tx := coinbaseTx()
+	root := chainhash.DoubleHashH(tx)
+	good := blockData(root[:], tx)
+	// claims 5 transactions but only holds one
+	broken := blockData(root[:], tx)
+	broken[8+112] = 5
+
+	var data []byte
+	data = append(data, good...)
+	data = append(data, "xyz"...)
+	data = append(data, broken...)
+	data = append(data, good...)
+	data = append(data, make([]byte, 16)...)
+
+	var corruptions []Corruption
+	blocks, err := New("blk00000.dat", 0, data, Options{OnCorruption: func(c Corruption) {
+		corruptions = append(corruptions, c)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		block, err := blocks.NextBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.Transactions[0].Hash != root.String() {
+			t.Errorf("unexpected block %d", i)
+		}
+	}
+	if _, err = blocks.NextBlock(); err == nil {
+		t.Fatal("expected the end of the file")
+	}
+
+	if len(corruptions) != 2 {
+		t.Fatalf("expected 2 corruptions, got %v", corruptions)
+	}
+	if c := corruptions[0]; c.Offset != int64(len(good)) || c.Skipped != 3 {
+		t.Errorf("unexpected corruption %s", c)
+	}
+	if c := corruptions[1]; c.Offset != int64(len(good)+3) || c.Skipped != int64(len(broken)) {
+		t.Errorf("unexpected corruption %s", c)
+	}
+}
+
+func TestStrictStreamFails(t *testing.T) {
+	tx := coinbaseTx()
+	root := chainhash.DoubleHashH(tx)
+	broken := blockData(root[:], tx)
+	broken[8+112] = 5
+	blocks, err := New("blk00000.dat", 0, broken, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = blocks.NextBlock(); err == nil {
+		t.Fatal("expected the broken block to fail")
+	}
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"bytes"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// witnessCommitmentHeader prefixes the coinbase output committing to the witness merkle root (BIP141).
+var witnessCommitmentHeader = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}
+
+// MerkleError reports a block whose transactions do not hash to the merkle root in its header or to the witness
+// commitment in its coinbase.
+type MerkleError struct {
+	BlockHash string
+	File      int
+	Witness   bool
+	Expected  string
+	Computed  string
+	Reason    string
+}
+
+func (e *MerkleError) Error() string {
+	root := "merkle root"
+	if e.Witness {
+		root = "witness commitment"
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("block %s in file %d has an invalid %s: %s", e.BlockHash, e.File, root, e.Reason)
+	}
+	return fmt.Sprintf("block %s in file %d has %s %s but its transactions hash to %s", e.BlockHash, e.File, root, e.Expected, e.Computed)
+}
+
+func validateMerkle(block *model.Block) error {
+	if len(block.Transactions) == 0 {
+		return &MerkleError{BlockHash: block.BlockHash, File: block.FileNumber, Reason: "block has no transactions"}
+	}
+	hashes := make([]chainhash.Hash, len(block.Transactions))
+	witnessHashes := make([]chainhash.Hash, len(block.Transactions))
+	var hasWitness bool
+	for i, tx := range block.Transactions {
+		hash, err := chainhash.NewHashFromStr(tx.Hash)
+		if err != nil {
+			return err
+		}
+		hashes[i] = *hash
+		if i == 0 {
+			continue // the coinbase witness hash is committed as zero
+		}
+		hash, err = chainhash.NewHashFromStr(tx.WitnessHash)
+		if err != nil {
+			return err
+		}
+		witnessHashes[i] = *hash
+		hasWitness = hasWitness || tx.IsSegWit
+	}
+
+	root := merkleRoot(hashes)
+	if !bytes.Equal(root[:], block.Header[36:68]) {
+		expected, _ := chainhash.NewHash(block.Header[36:68])
+		return &MerkleError{BlockHash: block.BlockHash, File: block.FileNumber, Expected: expected.String(), Computed: root.String()}
+	}
+
+	coinbase := block.Transactions[0]
+	var commitment []byte
+	for _, out := range coinbase.Outputs {
+		if out.ScriptType == lbrycrd.NullData && len(out.PKScript) >= 38 && bytes.HasPrefix(out.PKScript, witnessCommitmentHeader) {
+			commitment = out.PKScript[6:38]
+		}
+	}
+	if commitment == nil {
+		if hasWitness || coinbase.IsSegWit {
+			return &MerkleError{BlockHash: block.BlockHash, File: block.FileNumber, Witness: true, Reason: "witness data without a commitment"}
+		}
+		return nil
+	}
+	if len(coinbase.Witnesses) != 1 || len(coinbase.Witnesses[0].Bytes) != chainhash.HashSize {
+		return &MerkleError{BlockHash: block.BlockHash, File: block.FileNumber, Witness: true, Reason: "coinbase witness reserved value is missing"}
+	}
+	witnessRoot := merkleRoot(witnessHashes)
+	computed := chainhash.DoubleHashH(append(witnessRoot[:], coinbase.Witnesses[0].Bytes...))
+	if !bytes.Equal(computed[:], commitment) {
+		expected, _ := chainhash.NewHash(commitment)
+		return &MerkleError{BlockHash: block.BlockHash, File: block.FileNumber, Witness: true, Expected: expected.String(), Computed: computed.String()}
+	}
+	return nil
+}
+
+// merkleRoot hashes the transaction hashes pairwise, duplicating the last hash of odd levels.
+func merkleRoot(hashes []chainhash.Hash) chainhash.Hash {
+	if len(hashes) == 0 {
+		return chainhash.Hash{}
+	}
+	level := append([]chainhash.Hash{}, hashes...)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := 0; i < len(level); i += 2 {
+			level[i/2] = chainhash.DoubleHashH(append(level[i][:], level[i+1][:]...))
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0]
+}
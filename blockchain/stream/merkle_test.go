@@ -0,0 +1,71 @@
+package stream
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// coinbaseTx is a version 1 transaction with a single coinbase input and an anyone can spend output.
+func coinbaseTx() []byte {
+	tx := []byte{1, 0, 0, 0, 1}
+	tx = append(tx, make([]byte, 32)...)
+	tx = append(tx, 0xff, 0xff, 0xff, 0xff, 2, 0x51, 0x51, 0xff, 0xff, 0xff, 0xff, 1)
+	tx = append(tx, 0, 0xe1, 0xf5, 0x05, 0, 0, 0, 0, 1, 0x51)
+	return append(tx, 0, 0, 0, 0)
+}
+
+func blockData(merkleRoot []byte, tx []byte) []byte {
+	header := make([]byte, 112)
+	binary.LittleEndian.PutUint32(header[0:4], 1)
+	copy(header[36:68], merkleRoot)
+	body := append(header, 1)
+	body = append(body, tx...)
+	data := append([]byte{}, magicNumberConst...)
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(body)))
+	return append(append(data, size...), body...)
+}
+
+func TestValidateMerkle(t *testing.T) {
+	tx := coinbaseTx()
+	root := chainhash.DoubleHashH(tx)
+
+	blocks, err := New("", 0, blockData(root[:], tx), Options{ValidateMerkle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := blocks.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Transactions[0].Hash != root.String() {
+		t.Errorf("expected transaction %s, got %s", root, block.Transactions[0].Hash)
+	}
+
+	bad := root
+	bad[0] ^= 0xff
+	blocks, err = New("", 0, blockData(bad[:], tx), Options{ValidateMerkle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = blocks.NextBlock()
+	merkleErr, ok := err.(*MerkleError)
+	if !ok {
+		t.Fatalf("expected a merkle error, got %v", err)
+	}
+	if merkleErr.Computed != root.String() || merkleErr.Witness {
+		t.Errorf("unexpected merkle error %+v", merkleErr)
+	}
+}
+
+func TestMerkleRootOddLevels(t *testing.T) {
+	a, b, c := chainhash.Hash{1}, chainhash.Hash{2}, chainhash.Hash{3}
+	ab := chainhash.DoubleHashH(append(a[:], b[:]...))
+	cc := chainhash.DoubleHashH(append(c[:], c[:]...))
+	expected := chainhash.DoubleHashH(append(ab[:], cc[:]...))
+	if root := merkleRoot([]chainhash.Hash{a, b, c}); root != expected {
+		t.Errorf("expected %s, got %s", expected, root)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fast-blocks/util"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"io"
 	"os"
@@ -19,41 +20,128 @@ import (
 type Blocks interface {
 	NextBlock() (*model.Block, error)
 	BlockFile() string
+	Close() error
+}
+
+// Options tune how a blk file is read.
+type Options struct {
+	// Net is the network the blk file belongs to, its magic number separates the blocks. Defaults to mainnet.
+	Net wire.BitcoinNet
+	// UndoFile is the rev file stored alongside the blk file. When set every input gets the output it spends.
+	UndoFile string
+	// ValidateMerkle recomputes the merkle root and witness commitment of every block. NextBlock returns a
+	// *MerkleError for blocks that do not match their header.
+	ValidateMerkle bool
+	// OnCorruption makes the stream resilient. Instead of failing on a block it cannot read, the stream reports the
+	// corrupt region and resynchronises at the next magic number.
+	OnCorruption func(corruption Corruption)
 }
 
 type blockStream struct {
-	lastBlockHash  string
-	fileNr         int
-	blockNr        int
-	startingHeight int
-	offset         int64
-	path           string
-	file           *os.File
-	data           *bytes.Buffer
+	lastBlockHash string
+	fileNr        int
+	offset        int64
+	path          string
+	file          *os.File
+	data          *bytes.Reader
+	undos         Undos
+	opts          Options
+	magic         []byte
+	height        int
+	blockStart    int64
+	blockEnd      int64
+	resync        *Corruption
 	io.ReadCloser
 	io.Seeker
 }
 
-func New(path string, fileNr, height int, data []byte) (Blocks, error) {
+func New(path string, fileNr int, data []byte, opts Options) (Blocks, error) {
+	var undos Undos
+	if opts.UndoFile != "" {
+		var err error
+		undos, err = NewUndos(opts.UndoFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(data) == 0 {
 		file, err := os.OpenFile(path, os.O_RDONLY, 0)
 		if err != nil {
 			return nil, errors.Err(err)
 		}
-		return &blockStream{path: path, file: file, fileNr: fileNr, blockNr: height}, nil
+		return &blockStream{path: path, file: file, fileNr: fileNr, undos: undos, opts: opts, magic: magicNumber(opts.Net)}, nil
 	}
 
-	return &blockStream{path: path, data: bytes.NewBuffer(data)}, nil
+	return &blockStream{path: path, data: bytes.NewReader(data), undos: undos, opts: opts, magic: magicNumber(opts.Net)}, nil
+}
+
+// NewAt opens a blk file positioned at the magic number of the block stored at offset.
+func NewAt(path string, fileNr int, offset int64, opts Options) (Blocks, error) {
+	blocks, err := New(path, fileNr, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	bs := blocks.(*blockStream)
+	bs.offset, err = bs.file.Seek(offset, io.SeekStart)
+	if err != nil {
+		bs.Close()
+		return nil, errors.Err(err)
+	}
+	return bs, nil
 }
 
 func (bs blockStream) BlockFile() string {
 	return bs.file.Name()
 }
 
+func (bs *blockStream) Close() error {
+	if bs.file == nil {
+		return nil
+	}
+	return errors.Err(bs.file.Close())
+}
+
 func (bs *blockStream) NextBlock() (*model.Block, error) {
-	block := &model.Block{Height: bs.blockNr}
-	bs.blockNr = bs.blockNr + 1
-	err := bs.setBlockInfo(block)
+	var block *model.Block
+	for block == nil {
+		var err error
+		block, err = bs.readBlock()
+		if err == nil {
+			break
+		}
+		if bs.opts.OnCorruption == nil || errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if merkleErr, ok := err.(*MerkleError); ok {
+			// The block was read completely, only skip over it.
+			bs.opts.OnCorruption(Corruption{File: bs.path, Offset: bs.blockStart, Skipped: bs.offset - bs.blockStart, Reason: merkleErr.Error()})
+			continue
+		}
+		err = bs.startResync(bs.blockStart, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if bs.undos != nil {
+		err := bs.undos.Apply(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return block, nil //errors.Err(storage.DB.Exec(`INSERT INTO blocks VALUES ?`, &block))
+}
+
+func (bs *blockStream) readBlock() (block *model.Block, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Err("failed to parse block: %v", r)
+		}
+	}()
+	block = &model.Block{FileNumber: bs.fileNr}
+	bs.blockEnd = 0
+	err = bs.setBlockInfo(block)
 	if err != nil {
 		return nil, err
 	}
@@ -66,21 +154,44 @@ func (bs *blockStream) NextBlock() (*model.Block, error) {
 		block.TransactionHashes = append(block.TransactionHashes, t.Hash)
 		block.Transactions = append(block.Transactions, t)
 	}
+	if bs.offset != bs.blockEnd {
+		return nil, errors.Err("block %s ends at %d but its size says %d", block.BlockHash, bs.offset, bs.blockEnd)
+	}
 
-	return block, nil //errors.Err(storage.DB.Exec(`INSERT INTO blocks VALUES ?`, &block))
+	if bs.opts.ValidateMerkle {
+		err = validateMerkle(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return block, nil
 }
 
 var magicNumberConst = []byte{250, 228, 170, 241}
 
+// magicNumber returns the bytes starting every block of the network, the mainnet ones if net is not set.
+func magicNumber(net wire.BitcoinNet) []byte {
+	if net == 0 {
+		return magicNumberConst
+	}
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, uint32(net))
+	return magic
+}
+
 func (bs *blockStream) setBlockInfo(block *model.Block) error {
 	magicNumber, err := bs.readMagicNumber()
 	if err != nil {
 		return errors.Err(err)
 	}
+	bs.blockStart = bs.offset - 4
+	block.FileOffset = bs.blockStart
 	blockSize, _, err := bs.readUint32()
 	if err != nil {
 		return errors.Err(err)
 	}
+	bs.blockEnd = bs.offset + int64(blockSize)
 
 	header, err := bs.readBytes(112)
 	if err != nil {
@@ -108,43 +219,30 @@ func (bs *blockStream) setBlockInfo(block *model.Block) error {
 
 	// VALIDATION
 
-	for i, _ := range magicNumber {
-		if magicNumberConst[i] != magicNumber[i] {
-			println("failed to get constant magic number")
-		}
-	}
-
 	if block.Version > 1 && block.Version != 536870912 && block.Version != 536870913 {
-		panic("Block file reading is toast! Version should always be 1 or 536870912,536870913")
+		return errors.Err("unexpected block version %d, should always be 1 or 536870912,536870913", block.Version)
 	}
 
 	return nil
 }
 
-func (bs *blockStream) tell() (int64, error) {
-	if bs.data != nil {
-		return bs.offset, nil
-	}
-
-	offset, err := bs.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return 0, errors.Err(err)
-	}
-	return offset, nil
-}
-
 func (bs *blockStream) Seek(offset int64, whence int) (int64, error) {
+	var err error
 	if bs.data != nil {
-		return 0, nil
+		bs.offset, err = bs.data.Seek(offset, whence)
+	} else {
+		bs.offset, err = bs.file.Seek(offset, whence)
 	}
-	return bs.file.Seek(offset, whence)
+	return bs.offset, errors.Err(err)
 }
 
 func (bs *blockStream) Read(p []byte) (n int, err error) {
+	var read int
 	if bs.data != nil {
-		return bs.data.Read(p)
+		read, err = bs.data.Read(p)
+	} else {
+		read, err = bs.file.Read(p)
 	}
-	read, err := bs.file.Read(p)
 	bs.offset += int64(read)
 	return read, errors.Err(err)
 }
@@ -183,9 +281,12 @@ func (bs *blockStream) readCompactSize() (uint64, []byte, error) {
 	}
 
 	if size == 255 {
-		buf := make([]byte, 8)
+		v, buf, err := bs.readUint64()
+		if err != nil {
+			return 0, nil, err
+		}
 		readBuf = append(readBuf, buf...)
-		return binary.LittleEndian.Uint64(buf), readBuf, nil
+		return v, readBuf, nil
 	}
 
 	return 0, nil, errors.Err("size is greater than 255")
@@ -218,7 +319,7 @@ func (bs *blockStream) setTransactions(block *model.Block) ([]model.Transaction,
 				return nil, err
 			}
 			if !tx.IsSegWit {
-				panic("zero inputs and not segwit!!")
+				return nil, errors.Err("transaction %d of block %s has zero inputs and is not segwit", i, block.BlockHash)
 			}
 			//txBytes = append(txBytes, buf...) Not included for Segwit TxHash
 
@@ -240,6 +341,9 @@ func (bs *blockStream) setTransactions(block *model.Block) ([]model.Transaction,
 				return nil, err
 			}
 		}
+		if i == 0 && len(inputs) > 0 {
+			bs.height = coinbaseHeight(inputs[0].Script.Bytes())
+		}
 
 		tx.OutputCnt, buf, err = bs.readCompactSize()
 		if err != nil {
@@ -252,24 +356,28 @@ func (bs *blockStream) setTransactions(block *model.Block) ([]model.Transaction,
 			return nil, err
 		}
 
+		var witnessBytes []byte
 		if tx.IsSegWit {
 			for i := 0; i < int(tx.InputCnt); i++ {
-				nrWitnesses, _, err := bs.readCompactSize()
+				nrWitnesses, buf, err := bs.readCompactSize()
 				if err != nil {
 					return nil, err
 				}
+				witnessBytes = append(witnessBytes, buf...)
 
 				for i := 0; i < int(nrWitnesses); i++ {
 					witness := model.Witness{}
-					size, _, err := bs.readCompactSize()
+					size, buf, err := bs.readCompactSize()
 					if err != nil {
 						return nil, err
 					}
+					witnessBytes = append(witnessBytes, buf...)
 
 					witness.Bytes, err = bs.readBytes(int(size))
 					if err != nil {
 						return nil, err
 					}
+					witnessBytes = append(witnessBytes, witness.Bytes...)
 
 					tx.Witnesses = append(tx.Witnesses, witness)
 				}
@@ -283,10 +391,23 @@ func (bs *blockStream) setTransactions(block *model.Block) ([]model.Transaction,
 		txBytes = append(txBytes, buf...)
 
 		tx.Hash = chainhash.DoubleHashH(txBytes).String()
+		tx.WitnessHash = tx.Hash
+		if tx.IsSegWit {
+			// Marker and flag follow the version, witnesses precede the lock time
+			wtxBytes := append(append([]byte{}, txBytes[:4]...), 0, 1)
+			wtxBytes = append(wtxBytes, txBytes[4:len(txBytes)-4]...)
+			wtxBytes = append(wtxBytes, witnessBytes...)
+			wtxBytes = append(wtxBytes, txBytes[len(txBytes)-4:]...)
+			tx.WitnessHash = chainhash.DoubleHashH(wtxBytes).String()
+		}
 		tx.BlockHash = block.BlockHash
 		for _, out := range outputs {
 			out.TransactionHash = tx.Hash
 			out.BlockHash = block.BlockHash
+			err = setClaimID(&out)
+			if err != nil {
+				return nil, err
+			}
 			tx.Outputs = append(tx.Outputs, out)
 			//err := storage.DB.Exec(`INSERT INTO outputs VALUES ?`, &o)
 			//if err != nil {
@@ -385,91 +506,167 @@ func (bs *blockStream) setOutputs(tx *model.Transaction, txBytes []byte) ([]byte
 			return nil, nil, err
 		}
 		txBytes = append(txBytes, scriptBytes...)
-		pk, _ := txscript.ParsePkScript(scriptBytes)
-		scriptType := lbrycrd.GetPublicKeyScriptType(scriptBytes)
-		if pk.Class() != txscript.NonStandardTy {
-			address := lbrycrd.GetAddressFromPublicKeyScript(scriptBytes)
-			out.Address = model.Address{Encoded: address}
-			out.PKScript = scriptBytes
-			out.ScriptType = scriptType
-		} else if pk.Class() == txscript.NonStandardTy {
-			if lbrycrd.IsClaimScript(scriptBytes) {
-				txscript.NewScriptBuilder()
-				if lbrycrd.IsClaimNameScript(scriptBytes) {
-					name, _, pkscript, err := lbrycrd.ParseClaimNameScript(scriptBytes)
-					if err != nil {
-						return nil, nil, err
-					}
-					if false {
-						println("Name: ", name)
-					}
-					/*_, err = stake.DecodeClaimBytes(value, "lbrycrd_main")
-					if err != nil {
-						logrus.Error(err)
-						continue
-					}*/
-					addy := lbrycrd.GetAddressFromPublicKeyScript(pkscript)
-					if err != nil {
-						return nil, nil, err
-					}
-					out.Address = model.Address{Encoded: addy}
-					//println(helper.Claim.String())
-					//err = storage.DB.Exec(`INSERT INTO claims VALUES ?`, &helper.Claim)
-					//if err != nil {
-					//	return nil, nil, errors.Err(err)
-					//}
-				}
-			} else if lbrycrd.IsPurchaseScript(scriptBytes) {
-				purchase, err := lbrycrd.ParsePurchaseScript(scriptBytes)
-				if err != nil {
-					return nil, nil, err
-				}
-				if false {
-					println("Purchase: ", purchase.ClaimHash)
-				}
-
-			} else {
-				if false {
-					println(txscript.DisasmString(scriptBytes))
-					println("Non claim, no standard transaction")
-				}
-			}
+		out.Vout = uint32(i)
+		err = setOutputScript(&out, scriptBytes, bs.height)
+		if err != nil {
+			return nil, nil, err
 		}
 		outputs = append(outputs, out)
 	}
 	return txBytes, outputs, nil
 }
 
+// setOutputScript sets the address, script type and claim data of an output created at the height from its public key
+// script.
+func setOutputScript(out *model.Output, scriptBytes []byte, height int) error {
+	pk, _ := txscript.ParsePkScript(scriptBytes)
+	scriptType := lbrycrd.GetPublicKeyScriptType(scriptBytes)
+	if pk.Class() != txscript.NonStandardTy {
+		address := lbrycrd.GetAddressFromPublicKeyScript(scriptBytes)
+		out.Address = model.Address{Encoded: address}
+		out.PKScript = scriptBytes
+		out.ScriptType = scriptType
+	} else if pk.Class() == txscript.NonStandardTy {
+		if len(scriptBytes) > 0 && lbrycrd.IsClaimScript(scriptBytes) {
+			err := setClaimScript(out, scriptBytes, scriptType, height)
+			if err != nil {
+				// lbrycrd keeps the output as a nonstandard one when it does not decode as a claim script
+				*out = model.Output{Amount: out.Amount, Vout: out.Vout}
+			}
+		} else if lbrycrd.IsPurchaseScript(scriptBytes) {
+			purchase, err := lbrycrd.ParsePurchaseScript(scriptBytes)
+			if err != nil {
+				return err
+			}
+			out.Purchase = purchase
+			out.ClaimID = hex.EncodeToString(util.ReverseBytes(purchase.ClaimHash))
+			out.PKScript = scriptBytes
+			out.ScriptType = lbrycrd.NullData
+		} else if len(scriptBytes) > 0 && scriptBytes[0] == txscript.OP_RETURN {
+			out.PKScript = scriptBytes
+			out.ScriptType = lbrycrd.NullData
+		} else {
+			if false {
+				println(txscript.DisasmString(scriptBytes))
+				println("Non claim, no standard transaction")
+			}
+		}
+	}
+	return nil
+}
+
+// setClaimScript sets the claim data of an output from its claim, update or support script. The lbrycrd parsers
+// index the script without checking its length, a truncated script is returned as an error like other malformed ones.
+func setClaimScript(out *model.Output, scriptBytes []byte, scriptType string, height int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Err("failed to parse claim script: %v", r)
+		}
+	}()
+	var pkscript []byte
+	if lbrycrd.IsClaimNameScript(scriptBytes) {
+		out.ClaimOperation = lbrycrd.ClaimNameOp
+		out.ClaimName, out.ClaimValue, pkscript, err = lbrycrd.ParseClaimNameScript(scriptBytes)
+	} else if lbrycrd.IsClaimUpdateScript(scriptBytes) {
+		out.ClaimOperation = lbrycrd.UpdateClaimOp
+		out.ClaimName, out.ClaimID, out.ClaimValue, pkscript, err = lbrycrd.ParseClaimUpdateScript(scriptBytes)
+	} else if lbrycrd.IsClaimSupportScript(scriptBytes) {
+		out.ClaimOperation = lbrycrd.SupportClaimOp
+		out.ClaimName, out.ClaimID, out.ClaimValue, pkscript, err = lbrycrd.ParseClaimSupportDataScript(scriptBytes)
+		if err == nil && out.ClaimValue != nil && !lbrycrd.GetForks().SupportsWithData(height) {
+			return errors.Err("support with data at height %d before the fork", height)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if pkscript != nil {
+		out.Address = model.Address{Encoded: lbrycrd.GetAddressFromPublicKeyScript(pkscript)}
+		out.PKScript = scriptBytes
+		out.ScriptType = scriptType
+		out.InnerScriptType = lbrycrd.GetPublicKeyScriptType(pkscript)
+	}
+	if out.ClaimOperation == lbrycrd.SupportClaimOp {
+		if out.ClaimValue != nil {
+			out.Support, _ = lbrycrd.DecodeSupportValue(out.ClaimValue)
+		}
+	} else if out.ClaimValue != nil {
+		out.Claim, out.ClaimFormat, _ = lbrycrd.DecodeClaimValue(out.ClaimValue)
+	}
+	return nil
+}
+
+// coinbaseHeight returns the height BIP34 puts at the start of the coinbase script, 0 if there is none.
+func coinbaseHeight(script []byte) int {
+	if len(script) == 0 {
+		return 0
+	}
+	op := script[0]
+	if op >= txscript.OP_1 && op <= txscript.OP_16 {
+		return int(op - (txscript.OP_1 - 1))
+	}
+	if op == 0 || op > 8 || len(script) < int(op)+1 {
+		return 0
+	}
+	var height int
+	for i := int(op); i > 0; i-- {
+		height = height<<8 | int(script[i])
+	}
+	return height
+}
+
+// setClaimID derives the claim ID of a claim name output from its outpoint. Updates carry the ID in their script.
+func setClaimID(out *model.Output) error {
+	if out.ClaimID != "" || len(out.PKScript) == 0 || !lbrycrd.IsClaimNameScript(out.PKScript) {
+		return nil
+	}
+	claimID, err := util.ClaimIDFromOutpoint(out.TransactionHash, int(out.Vout))
+	if err != nil {
+		return errors.Err(err)
+	}
+	out.ClaimID = claimID
+	return nil
+}
+
 func (bs *blockStream) readBytes(toRead int) ([]byte, error) {
+	if bs.blockEnd > 0 && bs.offset+int64(toRead) > bs.blockEnd {
+		return nil, errors.Err("reading %d bytes at %d goes past the end of the block at %d", toRead, bs.offset, bs.blockEnd)
+	}
 	buf := make([]byte, toRead)
-	_, err := bs.Read(buf)
+	_, err := io.ReadFull(bs, buf)
 	if err != nil {
 		return nil, errors.Err(err)
 	}
 	return buf, nil
 }
 
+// readMagicNumber scans forward to the next magic number. Anything skipped on the way is reported as corruption in
+// resilient mode, except the zeros lbrycrd preallocates at the end of a blk file.
 func (bs *blockStream) readMagicNumber() ([]byte, error) {
+	start := bs.offset
 	var pos = 0
 	for pos < 4 {
-		magicPosByte := magicNumberConst[pos]
 		buf, err := bs.readBytes(1)
 		if err != nil {
+			if bs.resync != nil && errors.Is(err, io.EOF) {
+				bs.finishResync(bs.offset)
+			}
 			return nil, err
 		}
-		if magicPosByte == buf[0] {
+		if bs.magic[pos] == buf[0] {
 			pos++
-		} else if pos == 4 {
-			break
-		} else if pos > 0 {
-			if buf[0] == magicNumberConst[0] {
-				pos = 1
-			} else {
-				pos = 0 /// A, B, C, D => A, B, A, B, C, D
-			}
+		} else if buf[0] == bs.magic[0] {
+			pos = 1 /// A, B, C, D => A, B, A, B, C, D
+		} else {
+			pos = 0
 		}
 	}
-	return magicNumberConst, nil
+	if bs.resync != nil {
+		bs.finishResync(bs.offset - 4)
+	} else if skipped := bs.offset - 4 - start; skipped > 0 && bs.opts.OnCorruption != nil {
+		bs.opts.OnCorruption(Corruption{File: bs.path, Offset: start, Skipped: skipped, Reason: "data before magic number"})
+	}
+	return bs.magic, nil
 }
 
 func (bs *blockStream) readUint64() (uint64, []byte, error) {
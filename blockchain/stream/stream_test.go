@@ -0,0 +1,213 @@
+package stream
+
+import (
+	"encoding/hex"
+	"fast-blocks/lbrycrd"
+	"fast-blocks/util"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/golang/protobuf/proto"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func TestNetworkMagic(t *testing.T) {
+	tx := coinbaseTx()
+	root := chainhash.DoubleHashH(tx)
+	data := blockData(root[:], tx)
+	copy(data, []byte{0xfa, 0xe4, 0xaa, 0xd1}) // regtest
+
+	blocks, err := New("", 0, data, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = blocks.NextBlock(); err == nil {
+		t.Error("expected no mainnet block in regtest data")
+	}
+
+	blocks, err = New("", 0, data, Options{Net: wire.BitcoinNet(0xd1aae4fa)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := blocks.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Transactions[0].Hash != root.String() {
+		t.Errorf("unexpected block %+v", block)
+	}
+}
+
+// scriptTx is a coinbase transaction paying to the script.
+func scriptTx(script []byte) []byte {
+	tx := coinbaseTx()
+	tx = tx[:len(tx)-14]
+	tx = append(tx, 0, 0xe1, 0xf5, 0x05, 0, 0, 0, 0, byte(len(script)))
+	tx = append(tx, script...)
+	return append(tx, 0, 0, 0, 0)
+}
+
+func push(data []byte) []byte {
+	return append([]byte{byte(len(data))}, data...)
+}
+
+func TestClaimOutputs(t *testing.T) {
+	claim := &pb.Claim{Title: "fast blocks", Type: &pb.Claim_Stream{Stream: &pb.Stream{}}}
+	payload, err := proto.Marshal(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := append([]byte{0}, payload...)
+	pubKeyHash, _ := hex.DecodeString("5f7a5a5aab24884b74639e221388e443f1a0a5ef")
+	p2pkh := append(append([]byte{0x76, 0xa9, 20}, pubKeyHash...), 0x88, 0xac)
+	claimID, _ := hex.DecodeString("0123456789abcdef0123456789abcdef01234567")
+
+	nameScript := append([]byte{0xb5}, push([]byte("fast"))...)
+	nameScript = append(append(append(nameScript, push(value)...), 0x6d, 0x75), p2pkh...)
+	updateScript := append([]byte{0xb7}, push([]byte("fast"))...)
+	updateScript = append(append(updateScript, push(util.ReverseBytes(claimID))...), push(value)...)
+	updateScript = append(append(updateScript, 0x6d, 0x6d), p2pkh...)
+	supportScript := append([]byte{0xb6}, push([]byte("fast"))...)
+	supportScript = append(append(supportScript, push(util.ReverseBytes(claimID))...), 0x6d, 0x75)
+	supportScript = append(supportScript, p2pkh...)
+
+	for _, script := range [][]byte{nameScript, updateScript, supportScript} {
+		tx := scriptTx(script)
+		root := chainhash.DoubleHashH(tx)
+		blocks, err := New("", 0, blockData(root[:], tx), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := blocks.NextBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := block.Transactions[0].Outputs[0]
+		expectedID := hex.EncodeToString(claimID)
+		expectedOp := lbrycrd.UpdateClaimOp
+		switch script[0] {
+		case 0xb5:
+			expectedID, _ = util.ClaimIDFromOutpoint(root.String(), 0)
+			expectedOp = lbrycrd.ClaimNameOp
+		case 0xb6:
+			expectedOp = lbrycrd.SupportClaimOp
+		}
+		if out.ClaimName != "fast" || out.ClaimID != expectedID || out.ClaimOperation != expectedOp {
+			t.Errorf("unexpected claim output %+v", out)
+		}
+		if out.Address.Encoded != "bMS7TgmB7CUNB7FsimV2wi27YUNSpTNdSo" || out.InnerScriptType != "pubkeyhash" {
+			t.Errorf("unexpected claim address %+v", out)
+		}
+		if expectedOp == lbrycrd.SupportClaimOp {
+			if out.Claim != nil || out.ClaimValue != nil {
+				t.Errorf("unexpected support value %+v", out)
+			}
+		} else if out.Claim.GetTitle() != "fast blocks" || out.ClaimFormat != lbrycrd.ClaimFormatProtobufV2 {
+			t.Errorf("unexpected claim %s", out.Claim)
+		}
+	}
+}
+
+func TestPurchaseOutput(t *testing.T) {
+	claimID := "0123456789abcdef0123456789abcdef01234567"
+	claimHash, _ := hex.DecodeString(claimID)
+	payload, err := proto.Marshal(&pb.Purchase{ClaimHash: util.ReverseBytes(claimHash)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := append([]byte{0x6a}, push(append([]byte{'P'}, payload...))...)
+
+	tx := scriptTx(script)
+	root := chainhash.DoubleHashH(tx)
+	blocks, err := New("", 0, blockData(root[:], tx), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := blocks.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := block.Transactions[0].Outputs[0]
+	if out.Purchase == nil || out.ClaimID != claimID || out.ScriptType != lbrycrd.NullData {
+		t.Errorf("unexpected purchase output %+v", out)
+	}
+}
+
+func TestCoinbaseHeight(t *testing.T) {
+	tests := []struct {
+		script []byte
+		height int
+	}{{nil, 0}, {[]byte{0x51}, 1}, {[]byte{0x60}, 16}, {[]byte{1, 17}, 17}, {[]byte{3, 0x86, 0x0b, 0x0a, 0}, 658310}, {[]byte{2, 1}, 0}}
+	for _, test := range tests {
+		if h := coinbaseHeight(test.script); h != test.height {
+			t.Errorf("expected height %d for %x, got %d", test.height, test.script, h)
+		}
+	}
+}
+
+func TestSupportWithData(t *testing.T) {
+	payload, err := proto.Marshal(&pb.Support{Emoji: "🚀"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKeyHash, _ := hex.DecodeString("5f7a5a5aab24884b74639e221388e443f1a0a5ef")
+	claimID, _ := hex.DecodeString("0123456789abcdef0123456789abcdef01234567")
+	script := append([]byte{0xb6}, push([]byte("fast"))...)
+	script = append(append(script, push(util.ReverseBytes(claimID))...), push(append([]byte{0}, payload...))...)
+	script = append(append(script, 0x6d, 0x6d, 0x76, 0xa9, 20), pubKeyHash...)
+	script = append(script, 0x88, 0xac)
+
+	for _, height := range [][]byte{{3, 0x85, 0x0b, 0x0a}, {3, 0x86, 0x0b, 0x0a}} {
+		tx := scriptTx(script)
+		// the coinbase script starts at 41, after the version, input count and outpoint
+		tx = append(append(append([]byte{}, tx[:41]...), 4), append(height, tx[44:]...)...)
+		root := chainhash.DoubleHashH(tx)
+		blocks, err := New("", 0, blockData(root[:], tx), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := blocks.NextBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := block.Transactions[0].Outputs[0]
+		if height[1] == 0x85 {
+			if out.ClaimOperation != "" || out.Address.Encoded != "" {
+				t.Errorf("support with data before the fork should be nonstandard, got %+v", out)
+			}
+			continue
+		}
+		if out.ClaimOperation != lbrycrd.SupportClaimOp || out.ClaimID != hex.EncodeToString(claimID) || out.Support.GetEmoji() != "🚀" {
+			t.Errorf("unexpected support %+v", out)
+		}
+		if out.Address.Encoded != "bMS7TgmB7CUNB7FsimV2wi27YUNSpTNdSo" {
+			t.Errorf("unexpected support address %s", out.Address.Encoded)
+		}
+	}
+}
+
+func TestTruncatedClaimOutputs(t *testing.T) {
+	claimID, _ := hex.DecodeString("0123456789abcdef0123456789abcdef01234567")
+	// the name claims 9 bytes but the script ends after 4
+	nameScript := append([]byte{0xb5, 9}, []byte("fast")...)
+	updateScript := append([]byte{0xb7}, push([]byte("fast"))...)
+	updateScript = append(updateScript, push(util.ReverseBytes(claimID))[:10]...)
+
+	for _, script := range [][]byte{nameScript, updateScript} {
+		tx := scriptTx(script)
+		root := chainhash.DoubleHashH(tx)
+		blocks, err := New("", 0, blockData(root[:], tx), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := blocks.NextBlock()
+		if err != nil {
+			t.Fatalf("expected the block to be kept, got %v", err)
+		}
+		out := block.Transactions[0].Outputs[0]
+		if out.ClaimOperation != "" || out.ClaimID != "" || out.PKScript != nil || out.Amount != 100000000 {
+			t.Errorf("expected a plain output, got %+v", out)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/util"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"io"
+	"os"
+)
+
+const undoChecksumSize = 32
+
+// Undos holds the undo records lbrycrd writes to the rev file alongside a blk file. A record lists the outputs spent
+// by every non coinbase transaction of a block.
+type Undos interface {
+	// Apply sets the spent output of every input of the block. Blocks without an undo record, like stale blocks or
+	// blocks lbrycrd has not connected yet, are left untouched.
+	Apply(block *model.Block) error
+}
+
+type undoRecord struct {
+	data     []byte
+	checksum []byte
+	used     bool
+}
+
+type undoStream struct {
+	path    string
+	records []undoRecord
+	next    int
+}
+
+// NewUndos reads every undo record of a rev file.
+func NewUndos(path string) (Undos, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	us := &undoStream{path: path}
+	for offset := 0; offset+8 <= len(data); {
+		if binary.LittleEndian.Uint32(data[offset:offset+4]) == 0 {
+			break // lbrycrd preallocates rev files with zeros
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		start := offset + 8
+		end := start + size
+		if end+undoChecksumSize > len(data) {
+			return nil, errors.Err("undo record at %d in %s is truncated", offset, path)
+		}
+		us.records = append(us.records, undoRecord{data: data[start:end], checksum: data[end : end+undoChecksumSize]})
+		offset = end + undoChecksumSize
+	}
+	return us, nil
+}
+
+func (us *undoStream) Apply(block *model.Block) error {
+	record, err := us.find(block.PrevBlockHash)
+	if err != nil || record == nil {
+		return err
+	}
+	spent, err := decodeBlockUndo(record.data)
+	if err != nil {
+		return errors.Prefix("undo record of block "+block.BlockHash, err)
+	}
+	if len(spent) != len(block.Transactions)-1 {
+		return errors.Err("undo record of block %s has %d transactions, expected %d", block.BlockHash, len(spent), len(block.Transactions)-1)
+	}
+	for i, prevouts := range spent {
+		tx := &block.Transactions[i+1]
+		if len(prevouts) != len(tx.Inputs) {
+			return errors.Err("undo record of transaction %s has %d outputs, expected %d", tx.Hash, len(prevouts), len(tx.Inputs))
+		}
+		for j := range tx.Inputs {
+			in := &tx.Inputs[j]
+			prevout := prevouts[j]
+			prevout.TransactionHash = in.TxRef
+			prevout.Vout = in.Position
+			err = setClaimID(&prevout.Output)
+			if err != nil {
+				return err
+			}
+			in.Prevout = &prevout.Output
+			in.PrevoutHeight = prevout.height
+		}
+	}
+	return nil
+}
+
+// find returns the record written for the block following prevBlockHash. lbrycrd checksums each record together
+// with the hash of the previous block. Records are mostly in the same order as the blocks, so the search starts
+// after the last match.
+func (us *undoStream) find(prevBlockHash string) (*undoRecord, error) {
+	prev, err := hex.DecodeString(prevBlockHash)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	prev = util.ReverseBytes(prev)
+	for n := 0; n < len(us.records); n++ {
+		i := (us.next + n) % len(us.records)
+		record := &us.records[i]
+		if record.used {
+			continue
+		}
+		checksum := chainhash.DoubleHashH(append(append([]byte{}, prev...), record.data...))
+		if bytes.Equal(checksum[:], record.checksum) {
+			record.used = true
+			us.next = i + 1
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+type spentOutput struct {
+	model.Output
+	height int
+}
+
+// decodeBlockUndo decodes a CBlockUndo. See https://github.com/lbryio/lbrycrd/blob/master/src/undo.h
+func decodeBlockUndo(data []byte) ([][]spentOutput, error) {
+	r := bytes.NewReader(data)
+	txCnt, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if txCnt > uint64(len(data)) {
+		return nil, errors.Err("undo record claims %d transactions", txCnt)
+	}
+	spent := make([][]spentOutput, txCnt)
+	for i := range spent {
+		prevoutCnt, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		if prevoutCnt > uint64(len(data)) {
+			return nil, errors.Err("undo record claims %d spent outputs", prevoutCnt)
+		}
+		spent[i] = make([]spentOutput, prevoutCnt)
+		for j := range spent[i] {
+			spent[i][j], err = decodeTxInUndo(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.Err("%d bytes left after decoding undo record", r.Len())
+	}
+	return spent, nil
+}
+
+// decodeTxInUndo decodes a spent coin. Records written before lbrycrd 0.17 only carry the height for the last
+// output spent from a transaction, height is 0 for the others.
+func decodeTxInUndo(r *bytes.Reader) (spentOutput, error) {
+	var spent spentOutput
+	code, err := util.ReadVarInt(r)
+	if err != nil {
+		return spent, err
+	}
+	spent.height = int(code >> 1)
+	if spent.height > 0 {
+		_, err = util.ReadVarInt(r) // transaction version, unused since 0.15
+		if err != nil {
+			return spent, err
+		}
+	}
+	amount, err := util.ReadVarInt(r)
+	if err != nil {
+		return spent, err
+	}
+	spent.Amount = decompressAmount(amount)
+	pkScript, err := decompressScript(r)
+	if err != nil {
+		return spent, err
+	}
+	err = setOutputScript(&spent.Output, pkScript, spent.height)
+	if err != nil {
+		return spent, err
+	}
+	spent.PKScript = pkScript
+	return spent, nil
+}
+
+// decompressAmount reverses the amount compression of lbrycrd's compressor.cpp.
+func decompressAmount(x uint64) uint64 {
+	if x == 0 {
+		return 0
+	}
+	x--
+	e := x % 10
+	x /= 10
+	var n uint64
+	if e < 9 {
+		d := x%9 + 1
+		x /= 9
+		n = x*10 + d
+	} else {
+		n = x + 1
+	}
+	for ; e > 0; e-- {
+		n *= 10
+	}
+	return n
+}
+
+const numSpecialScripts = 6
+
+// decompressScript reverses the script compression of lbrycrd's compressor.cpp. The six special sizes encode pay to
+// pubkey hash, pay to script hash and pay to pubkey scripts, everything else is stored as is.
+func decompressScript(r *bytes.Reader) ([]byte, error) {
+	size, err := util.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if size >= numSpecialScripts {
+		size -= numSpecialScripts
+		if size > uint64(r.Len()) {
+			return nil, errors.Err("compressed script of %d bytes exceeds the undo record", size)
+		}
+		script := make([]byte, size)
+		_, err = io.ReadFull(r, script)
+		return script, errors.Err(err)
+	}
+
+	var payload []byte
+	if size < 2 {
+		payload = make([]byte, 20)
+	} else {
+		payload = make([]byte, 32)
+	}
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	switch size {
+	case 0:
+		return append(append([]byte{0x76, 0xa9, 20}, payload...), 0x88, 0xac), nil
+	case 1:
+		return append(append([]byte{0xa9, 20}, payload...), 0x87), nil
+	case 2, 3:
+		return append(append([]byte{33, byte(size)}, payload...), 0xac), nil
+	default:
+		pubKey, err := btcec.ParsePubKey(append([]byte{byte(size - 2)}, payload...), btcec.S256())
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		return append(append([]byte{65}, pubKey.SerializeUncompressed()...), 0xac), nil
+	}
+}
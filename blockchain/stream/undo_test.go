@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/util"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func TestDecompressAmount(t *testing.T) {
+	const coin = 100000000
+	// Vectors from lbrycrd's compress_tests.cpp
+	pairs := map[uint64]uint64{0x0: 0, 0x1: 1, 0x7: coin / 100, 0x9: coin, 0x32: 50 * coin, 0x1406f40: 21000000 * coin}
+	for compressed, amount := range pairs {
+		if decompressed := decompressAmount(compressed); decompressed != amount {
+			t.Errorf("expected %d for %x, got %d", amount, compressed, decompressed)
+		}
+	}
+}
+
+func TestUndoApply(t *testing.T) {
+	pubKeyHash, _ := hex.DecodeString("5f7a5a5aab24884b74639e221388e443f1a0a5ef")
+	// one spending transaction with one coin: height 5 not coinbase, dummy version, 1 LBC, compressed p2pkh
+	record := []byte{1, 1, 10, 0, 9, 0}
+	record = append(record, pubKeyHash...)
+
+	prevBlockHash := "9c89283ba0f3227f6c03b70216b9f665f0118d5e0fa729cedf4fb34d6a34f463"
+	prev, _ := hex.DecodeString(prevBlockHash)
+	checksum := chainhash.DoubleHashH(append(util.ReverseBytes(prev), record...))
+
+	var data []byte
+	data = append(data, magicNumberConst...)
+	data = append(data, make([]byte, 4)...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(record)))
+	data = append(data, record...)
+	data = append(data, checksum[:]...)
+	data = append(data, make([]byte, 64)...)
+	path := filepath.Join(t.TempDir(), "rev00000.dat")
+	err := os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	undos, err := NewUndos(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &model.Block{PrevBlockHash: prevBlockHash, Transactions: []model.Transaction{
+		{Inputs: []model.Input{{TxRef: "Coinbase"}}},
+		{Inputs: []model.Input{{TxRef: "aa", Position: 3}}},
+	}}
+	err = undos.Apply(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := block.Transactions[1].Inputs[0]
+	if in.Prevout == nil {
+		t.Fatal("expected input to be enriched")
+	}
+	if in.Prevout.Amount != 100000000 || in.PrevoutHeight != 5 || in.Prevout.Vout != 3 {
+		t.Errorf("unexpected prevout %+v at height %d", in.Prevout, in.PrevoutHeight)
+	}
+	if in.Prevout.Address.Encoded != "bMS7TgmB7CUNB7FsimV2wi27YUNSpTNdSo" {
+		t.Errorf("unexpected address %s", in.Prevout.Address.Encoded)
+	}
+	if block.Transactions[0].Inputs[0].Prevout != nil {
+		t.Error("coinbase input should not be enriched")
+	}
+}
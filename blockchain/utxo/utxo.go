@@ -0,0 +1,116 @@
+package utxo
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Outpoint identifies a transaction output.
+type Outpoint struct {
+	TxID string
+	Vout uint32
+}
+
+// Coin is an unspent output along with the height of the block that created it.
+type Coin struct {
+	Amount         uint64
+	ScriptType     string
+	Address        string
+	PKScript       []byte
+	ClaimOperation string
+	ClaimName      string
+	ClaimID        string
+	Height         int
+	Coinbase       bool
+}
+
+// Set is the set of unspent outputs at the tip of the blocks applied to it. Blocks must be applied in height order,
+// typically from Chain.OnBlock.
+type Set struct {
+	sync.Mutex
+	coins  map[Outpoint]Coin
+	height int
+	// missing counts the inputs spending an outpoint that was not in the set
+	missing int
+}
+
+func New() *Set {
+	return &Set{coins: make(map[Outpoint]Coin)}
+}
+
+// Get returns the coin held by the outpoint if it is unspent.
+func (s *Set) Get(txID string, vout uint32) (Coin, bool) {
+	s.Lock()
+	defer s.Unlock()
+	coin, ok := s.coins[Outpoint{TxID: txID, Vout: vout}]
+	return coin, ok
+}
+
+// Len returns the number of unspent outputs.
+func (s *Set) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.coins)
+}
+
+// Height returns the height of the last block applied.
+func (s *Set) Height() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.height
+}
+
+// Missing returns how many inputs spent an outpoint that was not unspent, which means blocks were skipped.
+func (s *Set) Missing() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.missing
+}
+
+// Apply spends the inputs and adds the outputs of every transaction of the block, in order, so outputs spent in the
+// block they are created in never stay in the set. Outputs that can never be spent are not added.
+func (s *Set) Apply(block model.Block) {
+	s.Lock()
+	defer s.Unlock()
+	s.height = block.Height
+	for i, tx := range block.Transactions {
+		coinbase := i == 0
+		if !coinbase {
+			for _, in := range tx.Inputs {
+				outpoint := Outpoint{TxID: in.TxRef, Vout: in.Position}
+				if _, ok := s.coins[outpoint]; !ok {
+					s.missing++
+					logrus.Warn("Block ", block.Height, " spends ", outpoint.TxID, ":", outpoint.Vout, " which is not unspent")
+					continue
+				}
+				delete(s.coins, outpoint)
+			}
+		}
+		for _, out := range tx.Outputs {
+			if out.ScriptType == lbrycrd.NullData {
+				continue
+			}
+			outpoint := Outpoint{TxID: tx.Hash, Vout: out.Vout}
+			if existing, ok := s.coins[outpoint]; ok {
+				// BIP30: a transaction may not reuse the ID of one with unspent outputs. The only way to do it is a
+				// duplicate coinbase, which like in bitcoin overwrites the earlier outputs, leaving them unspendable.
+				logrus.Warn("Transaction ", tx.Hash, " at height ", block.Height, " overwrites the unspent output ",
+					outpoint.Vout, " created at height ", existing.Height)
+			}
+			s.coins[outpoint] = Coin{
+				Amount:         out.Amount,
+				ScriptType:     out.ScriptType,
+				Address:        out.Address.Encoded,
+				PKScript:       out.PKScript,
+				ClaimOperation: out.ClaimOperation,
+				ClaimName:      out.ClaimName,
+				ClaimID:        out.ClaimID,
+				Height:         block.Height,
+				Coinbase:       coinbase,
+			}
+		}
+	}
+}
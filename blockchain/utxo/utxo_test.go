@@ -0,0 +1,45 @@
+package utxo
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"testing"
+)
+
+func output(vout uint32, amount uint64) model.Output {
+	return model.Output{Vout: vout, Amount: amount, ScriptType: "pubkeyhash"}
+}
+
+func TestSet(t *testing.T) {
+	set := New()
+	set.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: "cb1", Inputs: []model.Input{{}}, Outputs: []model.Output{output(0, 50), {Vout: 1, ScriptType: lbrycrd.NullData}}},
+		{Hash: "a", Inputs: []model.Input{{TxRef: "unknown"}}, Outputs: []model.Output{output(0, 10), output(1, 20)}},
+		// spends an output of the same block
+		{Hash: "b", Inputs: []model.Input{{TxRef: "a", Position: 1}}, Outputs: []model.Output{output(0, 19)}},
+	}})
+	if coin, ok := set.Get("cb1", 0); !ok || !coin.Coinbase || coin.Amount != 50 || coin.Height != 1 {
+		t.Errorf("unexpected coinbase coin %+v", coin)
+	}
+	if _, ok := set.Get("cb1", 1); ok {
+		t.Error("expected the null data output to be left out")
+	}
+	if _, ok := set.Get("a", 1); ok {
+		t.Error("expected a:1 to be spent")
+	}
+	if set.Len() != 3 || set.Missing() != 1 {
+		t.Errorf("expected 3 coins and 1 missing spend, got %d and %d", set.Len(), set.Missing())
+	}
+
+	// a duplicate coinbase overwrites the unspent outputs of the first one
+	set.Apply(model.Block{Height: 2, Transactions: []model.Transaction{
+		{Hash: "cb1", Inputs: []model.Input{{}}, Outputs: []model.Output{output(0, 40)}},
+		{Hash: "c", Inputs: []model.Input{{TxRef: "a", Position: 0}, {TxRef: "b", Position: 0}}, Outputs: []model.Output{output(0, 28)}},
+	}})
+	if coin, ok := set.Get("cb1", 0); !ok || coin.Amount != 40 || coin.Height != 2 {
+		t.Errorf("expected the second coinbase to overwrite the first, got %+v", coin)
+	}
+	if set.Len() != 2 || set.Height() != 2 {
+		t.Errorf("expected 2 coins at height 2, got %d at %d", set.Len(), set.Height())
+	}
+}
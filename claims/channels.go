@@ -0,0 +1,93 @@
+package claims
+
+import (
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/global"
+	"fast-blocks/lbrycrd"
+	"fast-blocks/util"
+
+	"github.com/lbryio/lbry.go/v2/schema/stake"
+)
+
+// Channels is the registry of channel public keys used to verify signed claims. Blocks must be applied in height
+// order, so a channel is known before the claims it signs.
+type Channels struct {
+	channels map[string]*stake.StakeHelper
+	// signedBy is the channel validly signing the current version of every claim
+	signedBy map[string]string
+}
+
+func NewChannels() *Channels {
+	return &Channels{channels: make(map[string]*stake.StakeHelper), signedBy: make(map[string]string)}
+}
+
+// Apply registers the channels created or updated by the block and verifies the signatures of its signed claims.
+// Purchases get the channel validly signing the purchased claim as their SigningChannelID.
+func (c *Channels) Apply(block *model.Block) {
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		for j := range tx.Outputs {
+			c.apply(tx, &tx.Outputs[j])
+		}
+	}
+}
+
+// Channel returns the decoded claim of a channel, nil if no channel has the claim ID.
+func (c *Channels) Channel(claimID string) *stake.StakeHelper {
+	return c.channels[claimID]
+}
+
+func (c *Channels) apply(tx *model.Transaction, out *model.Output) {
+	if out.Purchase != nil {
+		out.SigningChannelID = c.signedBy[out.ClaimID]
+		return
+	}
+	if out.ClaimOperation != lbrycrd.ClaimNameOp && out.ClaimOperation != lbrycrd.UpdateClaimOp {
+		return
+	}
+	if out.Claim != nil {
+		c.verify(tx, out)
+	}
+	if out.IsSignatureValid {
+		c.signedBy[out.ClaimID] = out.SigningChannelID
+	} else {
+		delete(c.signedBy, out.ClaimID)
+	}
+}
+
+func (c *Channels) verify(tx *model.Transaction, out *model.Output) {
+	helper, err := stake.DecodeClaimBytes(out.ClaimValue, global.BlockChainName)
+	if err != nil {
+		return
+	}
+	if helper.Claim.GetChannel() != nil {
+		c.channels[out.ClaimID] = helper
+	}
+	if helper.Signature == nil || len(helper.ClaimID) == 0 {
+		return
+	}
+
+	// lbryschema v1 signed over the channel and the claim address, the current scheme over the outpoint of the first
+	// input
+	var k string
+	if helper.LegacyClaim != nil {
+		out.SigningChannelID = hex.EncodeToString(helper.ClaimID)
+		k = out.Address.Encoded
+	} else {
+		out.SigningChannelID = hex.EncodeToString(util.ReverseBytes(helper.ClaimID))
+		if len(tx.Inputs) == 0 {
+			return
+		}
+		var err error
+		k, err = stake.GetOutpointHash(tx.Inputs[0].TxRef, tx.Inputs[0].Position)
+		if err != nil {
+			return
+		}
+	}
+	channel := c.channels[out.SigningChannelID]
+	if channel == nil {
+		return
+	}
+	out.IsSignatureValid, _ = helper.ValidateClaimSignature(channel, k, out.SigningChannelID, global.BlockChainName)
+}
@@ -0,0 +1,175 @@
+package claims
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"fast-blocks/util"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/golang/protobuf/proto"
+	"github.com/lbryio/lbry.go/v2/schema/keys"
+	"github.com/lbryio/lbry.go/v2/schema/stake"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+const (
+	channelID  = "cf3f7c898af87cc69b06a6ac7899efb9a4878fdb"
+	firstInput = "4c1df9e022e396859175f9bfa69b38e444db10fb53355fa99a0989a83bcdb82f"
+	firstVout  = 1
+)
+
+func channelOutput(t *testing.T, key *btcec.PrivateKey) model.Output {
+	der, err := keys.PublicKeyToDER(key.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := &pb.Claim{Type: &pb.Claim_Channel{Channel: &pb.Channel{PublicKey: der}}}
+	payload, err := proto.Marshal(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.Output{ClaimOperation: lbrycrd.ClaimNameOp, ClaimID: channelID, ClaimValue: append([]byte{0}, payload...), Claim: claim}
+}
+
+// signedOutput signs a stream claim the way the SDK does, over the outpoint of the first input, the channel and the
+// claim.
+func signedOutput(t *testing.T, key *btcec.PrivateKey, title string) model.Output {
+	claim := &pb.Claim{Title: title, Type: &pb.Claim_Stream{Stream: &pb.Stream{}}}
+	payload, err := proto.Marshal(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, _ := hex.DecodeString(firstInput)
+	outpoint := make([]byte, 4)
+	binary.LittleEndian.PutUint32(outpoint, firstVout)
+	outpoint = append(util.ReverseBytes(input), outpoint...)
+	channel, _ := hex.DecodeString(channelID)
+	digest := sha256.Sum256(append(append(outpoint, util.ReverseBytes(channel)...), payload...))
+	sig, err := key.Sign(digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := make([]byte, 64)
+	sig.R.FillBytes(signature[:32])
+	sig.S.FillBytes(signature[32:])
+
+	value := append(append([]byte{1}, util.ReverseBytes(channel)...), signature...)
+	return model.Output{ClaimOperation: lbrycrd.ClaimNameOp, ClaimValue: append(value, payload...), Claim: claim}
+}
+
+func TestChannelSignatures(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signedOutput(t, key, "signed")
+	tampered := signedOutput(t, key, "signed")
+	tampered.ClaimValue[30] ^= 0xff // inside the signature
+	inputs := []model.Input{{TxRef: firstInput, Position: firstVout}}
+
+	channels := NewChannels()
+	early := model.Block{Transactions: []model.Transaction{{Inputs: inputs, Outputs: []model.Output{signed}}}}
+	channels.Apply(&early)
+	out := early.Transactions[0].Outputs[0]
+	if out.SigningChannelID != channelID || out.IsSignatureValid {
+		t.Errorf("claim signed by an unknown channel should not be valid, got %+v", out)
+	}
+
+	block := model.Block{Transactions: []model.Transaction{
+		{Outputs: []model.Output{channelOutput(t, key)}},
+		{Inputs: inputs, Outputs: []model.Output{signed, tampered}},
+	}}
+	channels.Apply(&block)
+	if channels.Channel(channelID) == nil {
+		t.Fatal("expected the channel to be registered")
+	}
+	if out := block.Transactions[1].Outputs[0]; !out.IsSignatureValid {
+		t.Errorf("expected a valid signature, got %+v", out)
+	}
+	if out := block.Transactions[1].Outputs[1]; out.SigningChannelID != channelID || out.IsSignatureValid {
+		t.Errorf("expected an invalid signature, got %+v", out)
+	}
+}
+
+func TestPurchaseChannel(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signedOutput(t, key, "signed")
+	signed.ClaimID = "a8d2b3b5c0e0a7fbd84a2b7f4c5d6e7f8091a2b3"
+	unsigned := model.Output{ClaimOperation: lbrycrd.ClaimNameOp, ClaimID: "b8d2b3b5c0e0a7fbd84a2b7f4c5d6e7f8091a2b3", Claim: &pb.Claim{}}
+	purchase := func(claimID string) model.Output {
+		return model.Output{ClaimID: claimID, Purchase: &pb.Purchase{}}
+	}
+
+	channels := NewChannels()
+	block := model.Block{Transactions: []model.Transaction{
+		{Outputs: []model.Output{channelOutput(t, key)}},
+		{Inputs: []model.Input{{TxRef: firstInput, Position: firstVout}}, Outputs: []model.Output{signed, unsigned}},
+		{Outputs: []model.Output{purchase(signed.ClaimID), purchase(unsigned.ClaimID)}},
+	}}
+	channels.Apply(&block)
+	if out := block.Transactions[2].Outputs[0]; out.SigningChannelID != channelID {
+		t.Errorf("expected the purchase of the signed claim to name its channel, got %q", out.SigningChannelID)
+	}
+	if out := block.Transactions[2].Outputs[1]; out.SigningChannelID != "" {
+		t.Errorf("expected no channel for the purchase of an unsigned claim, got %q", out.SigningChannelID)
+	}
+}
+
+// vectorOutputs returns the channel and the claim it signed of a test vector of lbry.go's schema/stake package.
+func vectorOutputs(t *testing.T, channelClaimID, channelHex, signedHex string) (model.Output, model.Output) {
+	channelValue, _ := hex.DecodeString(channelHex)
+	signedValue, _ := hex.DecodeString(signedHex)
+	channelHelper, err := stake.DecodeClaimBytes(channelValue, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedHelper, err := stake.DecodeClaimBytes(signedValue, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := model.Output{ClaimOperation: lbrycrd.ClaimNameOp, ClaimID: channelClaimID, ClaimValue: channelValue, Claim: channelHelper.Claim}
+	signed := model.Output{ClaimOperation: lbrycrd.ClaimNameOp, ClaimValue: signedValue, Claim: signedHelper.Claim}
+	return channel, signed
+}
+
+func TestChannelSignatureVectors(t *testing.T) {
+	v2Channel, v2Signed := vectorOutputs(t, "e67323a67a42307410f9679bf7fb344a428eb75c",
+		"00125a0a583056301006072a8648ce3d020106052b8104000a034200045a0343c155302280da01ae0001b7295241eb03c42a837acf92ccb9680892f7db50fd1d3c14b28bb594e304f05fc4ae7c1f222a85d1d1a3461b3cfb9906f66cb5",
+		"015cb78e424a34fbf79b67f9107430427aa62373e69b4998a29ecec8f14a9e0a213a043ced8064c069d7e464b5fd3ccb92b45bd59b15c0e1bb27e3c366d43f86a9a6b5ad42647a1aad69a73ac50b19ae3ec978c2c70aa2010a99010a301c662f19abc461e7eddecf165adfa7fca569e209773f3db31241c1e297f0a8d5b3e4768828b065fbeb1d6776f61073f6121b3031202d20556e6d6173746572656420496d70756c7365732e377a187a22146170706c69636174696f6e2f782d6578742d377a32302eb61ea475017e28c013616a56c1219ba90dc35fffff453d9675146f648f66634e0d1516528d37aba9f5801229d9f2181a044e6f6e6542087465737420707562520062020801")
+	v1Channel, v1Signed := vectorOutputs(t, "251305ca93d4dbedb50dceb282ebcb7b07b7ac65",
+		"08011002225e0801100322583056301006072a8648ce3d020106052b8104000a03420004d015365a40f3e5c03c87227168e5851f44659837bcf6a3398ae633bc37d04ee19baeb26dc888003bd728146dbea39f5344bf8c52cedaf1a3a1623a0166f4a367",
+		"080110011ad7010801128f01080410011a0c47616d65206f66206c696665221047616d65206f66206c696665206769662a0b4a6f686e20436f6e776179322e437265617469766520436f6d6d6f6e73204174747269627574696f6e20342e3020496e7465726e6174696f6e616c38004224080110011a195569c917f18bf5d2d67f1346aa467b218ba90cdbf2795676da250000803f4a0052005a001a41080110011a30b6adf6e2a62950407ea9fb045a96127b67d39088678d2f738c359894c88d95698075ee6203533d3c204330713aa7acaf2209696d6167652f6769662a5c080110031a40c73fe1be4f1743c2996102eec6ce0509e03744ab940c97d19ddb3b25596206367ab1a3d2583b16c04d2717eeb983ae8f84fee2a46621ffa5c4726b30174c6ff82214251305ca93d4dbedb50dceb282ebcb7b07b7ac65")
+	v1Signed.Address.Encoded = "bSkUov7HMWpYBiXackDwRnR5ishhGHvtJt"
+	v1Moved := v1Signed
+	v1Moved.Address.Encoded = "bMS7TgmB7CUNB7FsimV2wi27YUNSpTNdSo"
+
+	channels := NewChannels()
+	block := model.Block{Transactions: []model.Transaction{
+		{Outputs: []model.Output{v2Channel, v1Channel}},
+		{Inputs: []model.Input{{TxRef: "becb96a4a2e66bd24f083772fe9da904654ea9b5f07cc5bfbee233355911ddb1"}}, Outputs: []model.Output{v2Signed, v1Signed, v1Moved}},
+		// the same claim spending another output of the first input's transaction
+		{Inputs: []model.Input{{TxRef: "becb96a4a2e66bd24f083772fe9da904654ea9b5f07cc5bfbee233355911ddb1", Position: 1}}, Outputs: []model.Output{v2Signed}},
+	}}
+	channels.Apply(&block)
+	for _, test := range []struct {
+		out       model.Output
+		channelID string
+		valid     bool
+	}{
+		{block.Transactions[1].Outputs[0], "e67323a67a42307410f9679bf7fb344a428eb75c", true},
+		{block.Transactions[1].Outputs[1], "251305ca93d4dbedb50dceb282ebcb7b07b7ac65", true},
+		{block.Transactions[1].Outputs[2], "251305ca93d4dbedb50dceb282ebcb7b07b7ac65", false},
+		{block.Transactions[2].Outputs[0], "e67323a67a42307410f9679bf7fb344a428eb75c", false},
+	} {
+		if test.out.SigningChannelID != test.channelID || test.out.IsSignatureValid != test.valid {
+			t.Errorf("expected channel %s with valid %t, got %s with valid %t", test.channelID, test.valid, test.out.SigningChannelID, test.out.IsSignatureValid)
+		}
+	}
+}
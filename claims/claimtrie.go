@@ -0,0 +1,471 @@
+package claims
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"fast-blocks/util"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/sirupsen/logrus"
+)
+
+// A claim or support added to a name waits one block for every 32 blocks since the name's last takeover, at most
+// 4032 blocks, before it is active.
+const (
+	proportionalDelayFactor = 32
+	maxActiveDelay          = 4032
+)
+
+// TrieClaim is a version of a claim in the claimtrie, from its AcceptedAt height until its RemovedAt height. A
+// takeover of the name moves ActiveAt forward to the takeover height.
+type TrieClaim struct {
+	ClaimID    string
+	Outpoint   Outpoint
+	Amount     uint64
+	Address    string
+	AcceptedAt int
+	ActiveAt   int
+	// RemovedAt is 0 while the claim is in the claimtrie.
+	RemovedAt int
+	// NormalizedAt is the NormalizedName fork height for claims it moved to their normalized name, 0 otherwise.
+	NormalizedAt int
+}
+
+// TrieSupport is a support in the claimtrie, the fields match TrieClaim.
+type TrieSupport TrieClaim
+
+// Takeover is a change of the controlling claim of a name. ClaimID is empty when the name lost all its claims.
+type Takeover struct {
+	Height  int
+	ClaimID string
+}
+
+// ClaimState is a claim of a name at a height. EffectiveAmount adds the active supports to the claim's amount.
+type ClaimState struct {
+	ClaimID         string
+	Outpoint        Outpoint
+	Amount          uint64
+	EffectiveAmount uint64
+	AcceptedAt      int
+	ActiveAt        int
+	Active          bool
+}
+
+// NameState is a name of the claimtrie at a height. Its claims are ordered from the best to the worst.
+type NameState struct {
+	Name           string
+	Height         int
+	Controlling    string
+	TakeoverHeight int
+	Claims         []ClaimState
+}
+
+type trieNode struct {
+	name string
+	// claims and supports hold every version ever added, live only those still in the claimtrie.
+	claims       []*TrieClaim
+	supports     []*TrieSupport
+	liveClaims   []*TrieClaim
+	liveSupports []*TrieSupport
+	takeovers    []Takeover
+}
+
+// ClaimTrie replays lbrycrd's claimtrie: claims and supports become active after a delay depending on the last
+// takeover of their name, and the active claim with the largest effective amount controls the name. Blocks must be
+// applied in height order, the claimtrie keeps every version so names can be queried as of any height. From the
+// NormalizedName fork on names compete in their normalized form. The takeover workaround of mainnet uses the names
+// embedded in the lbrycrd package, without them roots diverge from lbrycrd within its heights.
+type ClaimTrie struct {
+	sync.Mutex
+	tracker     *Tracker
+	height      int
+	nodes       map[string]*trieNode
+	dirty       map[string]bool
+	activations map[int][]string
+	hashes      *hashTrie
+	divergence  *RootError
+	onApplyFns  []func(height int, changed []*trieNode)
+}
+
+// NewClaimTrie returns an empty claimtrie fed by its own claim tracker.
+func NewClaimTrie() *ClaimTrie {
+	c := &ClaimTrie{
+		tracker:     NewTracker(),
+		nodes:       make(map[string]*trieNode),
+		dirty:       make(map[string]bool),
+		activations: make(map[int][]string),
+		hashes:      newHashTrie(),
+	}
+	c.tracker.OnEvent(c.onEvent)
+	return c
+}
+
+// Tracker returns the tracker following the lifecycle of the claims in the claimtrie.
+func (c *ClaimTrie) Tracker() *Tracker {
+	return c.tracker
+}
+
+// Height returns the height of the last block applied.
+func (c *ClaimTrie) Height() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.height
+}
+
+// Divergence returns the first block whose claimtrie root differs from the replayed claimtrie, nil if none did.
+func (c *ClaimTrie) Divergence() *RootError {
+	c.Lock()
+	defer c.Unlock()
+	return c.divergence
+}
+
+// RootHash returns the claimtrie root as of the last block applied.
+func (c *ClaimTrie) RootHash() chainhash.Hash {
+	c.Lock()
+	defer c.Unlock()
+	return c.rootHash()
+}
+
+// rootHash hashes the claimtrie of the last block. lbrycrd hashes a block with the rules of the next height, so the
+// root switches algorithm at the block before the AllClaimsInMerkle fork.
+func (c *ClaimTrie) rootHash() chainhash.Hash {
+	return c.hashes.rootHash(c.height, c.height+1 >= lbrycrd.GetForks().AllClaimsInMerkle)
+}
+
+// Apply processes the claims and supports of the block, then activates and takes over names at its height. The
+// claimtrie root of the block is compared to the replayed claimtrie and the first divergence is kept.
+func (c *ClaimTrie) Apply(block model.Block) {
+	c.Lock()
+	defer c.Unlock()
+	c.height = block.Height
+	forks := lbrycrd.GetForks()
+	if block.Height == forks.MinTakeoverWorkaround && !lbrycrd.TakeoverWorkaroundsLoaded() {
+		logrus.Error("Takeover workarounds are not loaded, names will differ from lbrycrd until height ",
+			forks.MaxTakeoverWorkaround, ", run go generate ./lbrycrd or provide takeovers.txt")
+	}
+	if block.Height == forks.NormalizedName {
+		c.normalize(block.Height)
+	}
+	c.tracker.Apply(block)
+	for _, name := range c.activations[block.Height] {
+		c.dirty[name] = true
+	}
+	delete(c.activations, block.Height)
+	names := make([]string, 0, len(c.dirty))
+	for name := range c.dirty {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	changed := make([]*trieNode, 0, len(names))
+	for _, name := range names {
+		n := c.nodes[name]
+		n.takeover(block.Height, forks.TakeoverWorkaround(block.Height, name))
+		c.hashes.touch(n)
+		changed = append(changed, n)
+	}
+	c.dirty = make(map[string]bool)
+	for _, fn := range c.onApplyFns {
+		fn(block.Height, changed)
+	}
+	if c.divergence == nil && block.ClaimTrieRoot != "" {
+		c.verify(block)
+	}
+}
+
+func (c *ClaimTrie) verify(block model.Block) {
+	root, err := hex.DecodeString(block.ClaimTrieRoot)
+	if err != nil || len(root) != chainhash.HashSize {
+		return
+	}
+	expected, _ := chainhash.NewHash(root)
+	if computed := c.rootHash(); computed != *expected {
+		c.divergence = &RootError{Height: block.Height, BlockHash: block.BlockHash, Expected: expected.String(), Computed: computed.String()}
+		logrus.Error(c.divergence)
+	}
+}
+
+// Controlling returns the claim ID controlling the name at the height.
+func (c *ClaimTrie) Controlling(name string, height int) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	n, ok := c.nodes[lbrycrd.GetForks().TrieName(name, height)]
+	if !ok {
+		return "", false
+	}
+	takeover, ok := n.takeoverAt(height)
+	return takeover.ClaimID, ok && takeover.ClaimID != ""
+}
+
+// NameAt returns the claims of the name at the height. The name is normalized from the NormalizedName fork on.
+func (c *ClaimTrie) NameAt(name string, height int) (*NameState, bool) {
+	c.Lock()
+	defer c.Unlock()
+	name = lbrycrd.GetForks().TrieName(name, height)
+	n, ok := c.nodes[name]
+	if !ok {
+		return nil, false
+	}
+	state := &NameState{Name: name, Height: height}
+	if takeover, ok := n.takeoverAt(height); ok {
+		state.Controlling = takeover.ClaimID
+		state.TakeoverHeight = takeover.Height
+	}
+	for _, claim := range n.claims {
+		if !claim.existsAt(height) {
+			continue
+		}
+		state.Claims = append(state.Claims, ClaimState{
+			ClaimID:         claim.ClaimID,
+			Outpoint:        claim.Outpoint,
+			Amount:          claim.Amount,
+			EffectiveAmount: n.effectiveAmount(claim, height),
+			AcceptedAt:      claim.AcceptedAt,
+			ActiveAt:        claim.ActiveAt,
+			Active:          claim.ActiveAt <= height,
+		})
+	}
+	if len(state.Claims) == 0 && state.Controlling == "" {
+		return nil, false
+	}
+	sort.SliceStable(state.Claims, func(i, j int) bool {
+		a, b := state.Claims[i], state.Claims[j]
+		if a.Active != b.Active {
+			return a.Active
+		}
+		return better(a.EffectiveAmount, a.AcceptedAt, a.Outpoint, b.EffectiveAmount, b.AcceptedAt, b.Outpoint)
+	})
+	return state, true
+}
+
+// onEvent applies a lifecycle event from the tracker. It runs within Apply.
+func (c *ClaimTrie) onEvent(event Event) {
+	n := c.node(lbrycrd.GetForks().TrieName(event.Name, event.Height))
+	outpoint := Outpoint{TxID: event.TxID, Vout: event.Vout}
+	switch event.Type {
+	case EventCreate, EventUpdate:
+		if event.Type == EventUpdate {
+			n.removeClaimID(event.ClaimID, event.Height)
+		}
+		claim := &TrieClaim{ClaimID: event.ClaimID, Outpoint: outpoint, Amount: event.Amount, Address: event.Address, AcceptedAt: event.Height}
+		claim.ActiveAt = event.Height + n.delay(event.ClaimID, event.Height)
+		n.claims = append(n.claims, claim)
+		n.liveClaims = append(n.liveClaims, claim)
+		c.schedule(n.name, claim.ActiveAt, event.Height)
+	case EventAbandon, EventExpire:
+		n.removeClaim(outpoint, event.Height)
+	case EventSupport:
+		support := &TrieSupport{ClaimID: event.ClaimID, Outpoint: outpoint, Amount: event.Amount, Address: event.Address, AcceptedAt: event.Height}
+		support.ActiveAt = event.Height + n.delay(event.ClaimID, event.Height)
+		n.supports = append(n.supports, support)
+		n.liveSupports = append(n.liveSupports, support)
+		c.schedule(n.name, support.ActiveAt, event.Height)
+	case EventSupportRemove:
+		n.removeSupport(outpoint, event.Height)
+	}
+	c.dirty[n.name] = true
+}
+
+func (c *ClaimTrie) node(name string) *trieNode {
+	n, ok := c.nodes[name]
+	if !ok {
+		n = &trieNode{name: name}
+		c.nodes[name] = n
+	}
+	return n
+}
+
+// normalize moves the claims and supports of every name to its normalized form at the NormalizedName fork. They keep
+// their heights, the names they leave and join take over at the fork like any other change.
+func (c *ClaimTrie) normalize(height int) {
+	var names []string
+	for name := range c.nodes {
+		if lbrycrd.NormalizeName(name) != name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		from := c.nodes[name]
+		if len(from.liveClaims) == 0 && len(from.liveSupports) == 0 {
+			continue
+		}
+		to := c.node(lbrycrd.NormalizeName(name))
+		for _, claim := range from.liveClaims {
+			moved := *claim
+			moved.NormalizedAt = height
+			claim.RemovedAt = height
+			to.claims = append(to.claims, &moved)
+			to.liveClaims = append(to.liveClaims, &moved)
+			c.schedule(to.name, moved.ActiveAt, height)
+		}
+		for _, support := range from.liveSupports {
+			moved := *support
+			moved.NormalizedAt = height
+			support.RemovedAt = height
+			to.supports = append(to.supports, &moved)
+			to.liveSupports = append(to.liveSupports, &moved)
+			c.schedule(to.name, moved.ActiveAt, height)
+		}
+		from.liveClaims, from.liveSupports = nil, nil
+		c.dirty[from.name] = true
+		c.dirty[to.name] = true
+	}
+}
+
+func (c *ClaimTrie) schedule(name string, activeAt, height int) {
+	if activeAt > height {
+		c.activations[activeAt] = append(c.activations[activeAt], name)
+	}
+}
+
+// delay returns how long a claim or support added at the height waits before it is active. Updates and supports of
+// the controlling claim and anything added to a name without one are active right away.
+func (n *trieNode) delay(claimID string, height int) int {
+	controlling := n.controlling()
+	if controlling.ClaimID == "" || controlling.ClaimID == claimID {
+		return 0
+	}
+	delay := (height - controlling.Height) / proportionalDelayFactor
+	if delay > maxActiveDelay {
+		return maxActiveDelay
+	}
+	return delay
+}
+
+func (n *trieNode) controlling() Takeover {
+	if len(n.takeovers) == 0 {
+		return Takeover{}
+	}
+	return n.takeovers[len(n.takeovers)-1]
+}
+
+func (n *trieNode) takeoverAt(height int) (Takeover, bool) {
+	i := sort.Search(len(n.takeovers), func(i int) bool { return n.takeovers[i].Height > height })
+	if i == 0 {
+		return Takeover{}, false
+	}
+	return n.takeovers[i-1], true
+}
+
+// takeover changes the controlling claim if another active claim is now the best. A takeover activates every claim
+// and support still waiting on the name. The takeover workaround forces one even if the best claim stays the same.
+func (n *trieNode) takeover(height int, workaround bool) {
+	best := n.best(height)
+	controlling := n.controlling()
+	if best == nil && controlling.ClaimID == "" || best != nil && best.ClaimID == controlling.ClaimID && !workaround {
+		return
+	}
+	for _, claim := range n.liveClaims {
+		if claim.ActiveAt > height {
+			claim.ActiveAt = height
+		}
+	}
+	for _, support := range n.liveSupports {
+		if support.ActiveAt > height {
+			support.ActiveAt = height
+		}
+	}
+	takeover := Takeover{Height: height}
+	if best = n.best(height); best != nil {
+		takeover.ClaimID = best.ClaimID
+	}
+	n.takeovers = append(n.takeovers, takeover)
+}
+
+// best returns the active claim with the largest effective amount, the oldest one on a tie.
+func (n *trieNode) best(height int) *TrieClaim {
+	supported := make(map[string]uint64)
+	for _, support := range n.liveSupports {
+		if support.ActiveAt <= height {
+			supported[support.ClaimID] += support.Amount
+		}
+	}
+	var best *TrieClaim
+	var bestAmount uint64
+	for _, claim := range n.liveClaims {
+		if claim.ActiveAt > height {
+			continue
+		}
+		amount := claim.Amount + supported[claim.ClaimID]
+		if best == nil || better(amount, claim.AcceptedAt, claim.Outpoint, bestAmount, best.AcceptedAt, best.Outpoint) {
+			best, bestAmount = claim, amount
+		}
+	}
+	return best
+}
+
+// effectiveAmount returns the amount of the claim and the supports active for it at the height.
+func (n *trieNode) effectiveAmount(claim *TrieClaim, height int) uint64 {
+	amount := claim.Amount
+	for _, support := range n.supports {
+		if support.ClaimID == claim.ClaimID && support.ActiveAt <= height && (*TrieClaim)(support).existsAt(height) {
+			amount += support.Amount
+		}
+	}
+	return amount
+}
+
+func (n *trieNode) removeClaim(outpoint Outpoint, height int) {
+	n.removeClaims(func(claim *TrieClaim) bool { return claim.Outpoint == outpoint }, height)
+}
+
+func (n *trieNode) removeClaimID(claimID string, height int) {
+	n.removeClaims(func(claim *TrieClaim) bool { return claim.ClaimID == claimID }, height)
+}
+
+func (n *trieNode) removeClaims(match func(claim *TrieClaim) bool, height int) {
+	live := n.liveClaims[:0]
+	for _, claim := range n.liveClaims {
+		if match(claim) {
+			claim.RemovedAt = height
+			continue
+		}
+		live = append(live, claim)
+	}
+	n.liveClaims = live
+}
+
+func (n *trieNode) removeSupport(outpoint Outpoint, height int) {
+	live := n.liveSupports[:0]
+	for _, support := range n.liveSupports {
+		if support.Outpoint == outpoint {
+			support.RemovedAt = height
+			continue
+		}
+		live = append(live, support)
+	}
+	n.liveSupports = live
+}
+
+// existsAt returns true if the claim is under its name at the height.
+func (c *TrieClaim) existsAt(height int) bool {
+	if c.NormalizedAt > height {
+		return false
+	}
+	return c.AcceptedAt <= height && (c.RemovedAt == 0 || c.RemovedAt > height)
+}
+
+// better orders claims like lbrycrd: the larger effective amount, then the lower height, then the lower outpoint.
+func better(amount uint64, height int, outpoint Outpoint, otherAmount uint64, otherHeight int, other Outpoint) bool {
+	if amount != otherAmount {
+		return amount > otherAmount
+	}
+	if height != otherHeight {
+		return height < otherHeight
+	}
+	return outpointLess(outpoint, other)
+}
+
+// outpointLess compares outpoints the way lbrycrd does, on the transaction hash in its internal byte order.
+func outpointLess(a, b Outpoint) bool {
+	if a.TxID != b.TxID {
+		aHash, _ := hex.DecodeString(a.TxID)
+		bHash, _ := hex.DecodeString(b.TxID)
+		return bytes.Compare(util.ReverseBytes(aHash), util.ReverseBytes(bHash)) < 0
+	}
+	return a.Vout < b.Vout
+}
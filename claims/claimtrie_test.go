@@ -0,0 +1,128 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/global"
+	"fast-blocks/lbrycrd"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClaimTrieTakeover(t *testing.T) {
+	trie := NewClaimTrie()
+	blocks := map[int][]model.Transaction{
+		1: {{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "name", "ca", 0, 10)}}},
+		// 64 blocks after the takeover of ca, cb waits 2 blocks
+		65: {{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "name", "cb", 0, 20)}}},
+		// supports of the controlling claim are active right away
+		66:  {{Hash: "c", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "name", "ca", 0, 15)}}},
+		100: {{Hash: "d", Inputs: []model.Input{spend("c", 0)}}},
+	}
+	for height := 1; height <= 100; height++ {
+		trie.Apply(model.Block{Height: height, Transactions: blocks[height]})
+	}
+
+	for height, expected := range map[int]string{1: "ca", 66: "ca", 67: "ca", 99: "ca", 100: "cb"} {
+		if controlling, ok := trie.Controlling("name", height); !ok || controlling != expected {
+			t.Errorf("height %d: expected %s to control, got %s", height, expected, controlling)
+		}
+	}
+	if _, ok := trie.Controlling("name", 0); ok {
+		t.Error("expected no controlling claim before the first block")
+	}
+
+	state, ok := trie.NameAt("name", 67)
+	if !ok || len(state.Claims) != 2 || state.TakeoverHeight != 1 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if a := state.Claims[0]; a.ClaimID != "ca" || a.EffectiveAmount != 25 || !a.Active {
+		t.Errorf("unexpected best claim %+v", a)
+	}
+	if b := state.Claims[1]; b.ClaimID != "cb" || b.ActiveAt != 67 || !b.Active {
+		t.Errorf("unexpected second claim %+v", b)
+	}
+	if state, _ := trie.NameAt("name", 66); state.Claims[1].Active {
+		t.Errorf("expected cb to wait for its activation, got %+v", state.Claims[1])
+	}
+
+	state, _ = trie.NameAt("name", 100)
+	if state.Controlling != "cb" || state.TakeoverHeight != 100 || state.Claims[1].EffectiveAmount != 10 {
+		t.Errorf("unexpected state after the takeover %+v", state)
+	}
+}
+
+func TestClaimTrieNormalization(t *testing.T) {
+	global.BlockChainName = "lbrycrd_regtest"
+	defer func() { global.BlockChainName = "lbrycrd_main" }()
+	fork := lbrycrd.GetForks().NormalizedName
+
+	trie := NewClaimTrie()
+	blocks := map[int][]model.Transaction{
+		1: {{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "Foo", "ca", 0, 10)}}},
+		2: {{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "foo", "cb", 0, 5)}}},
+		// the update only matches the name in normalized form
+		fork + 1: {{Hash: "c", Inputs: []model.Input{spend("a", 0)}, Outputs: []model.Output{claimOutput(lbrycrd.UpdateClaimOp, "FOO", "ca", 0, 10)}}},
+	}
+	for height := 1; height <= fork+1; height++ {
+		trie.Apply(model.Block{Height: height, Transactions: blocks[height]})
+	}
+
+	for _, test := range []struct {
+		name     string
+		height   int
+		expected string
+	}{{"Foo", fork - 1, "ca"}, {"foo", fork - 1, "cb"}, {"FOO", fork, "ca"}, {"foo", fork + 1, "ca"}} {
+		if controlling, _ := trie.Controlling(test.name, test.height); controlling != test.expected {
+			t.Errorf("expected %s to control %s at %d, got %s", test.expected, test.name, test.height, controlling)
+		}
+	}
+	if state, _ := trie.NameAt("foo", fork-1); len(state.Claims) != 1 {
+		t.Errorf("expected the claims of Foo to join foo at the fork, got %+v", state.Claims)
+	}
+	if state, _ := trie.NameAt("Foo", fork); len(state.Claims) != 2 || state.TakeoverHeight != fork {
+		t.Errorf("unexpected state at the fork %+v", state)
+	}
+	if claim, _ := trie.Tracker().Claim("ca"); claim.UpdateHeight != fork+1 || claim.Name != "FOO" || claim.NormalizedName != "foo" {
+		t.Errorf("unexpected claim %+v", claim)
+	}
+}
+
+func TestClaimTrieTakeoverWorkaround(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "takeovers.txt")
+	err := os.WriteFile(path, []byte("496856_listed\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lbrycrd.LoadTakeoverWorkarounds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resetTakeoverWorkarounds(t)
+
+	trie := NewClaimTrie()
+	trie.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "listed", "ca", 0, 10)}},
+		{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "other", "cb", 0, 10)}},
+	}})
+	// supporting the controlling claim changes both names at a listed height, only the listed one is taken over
+	trie.Apply(model.Block{Height: 496856, Transactions: []model.Transaction{
+		{Hash: "c", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "listed", "ca", 0, 5)}},
+		{Hash: "d", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "other", "cb", 0, 5)}},
+	}})
+	for name, expected := range map[string]int{"listed": 496856, "other": 1} {
+		state, ok := trie.NameAt(name, 496856)
+		if !ok || state.TakeoverHeight != expected || state.Controlling != state.Claims[0].ClaimID {
+			t.Errorf("expected %s to be taken over at %d, got %+v", name, expected, state)
+		}
+	}
+}
+
+// resetTakeoverWorkarounds restores the takeover workarounds embedded in the lbrycrd package.
+func resetTakeoverWorkarounds(t *testing.T) {
+	keys, err := lbrycrd.DefaultTakeoverWorkarounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lbrycrd.SetTakeoverWorkarounds(keys)
+}
@@ -0,0 +1,142 @@
+package claims
+
+import (
+	"encoding/hex"
+	"fast-blocks/util"
+	"sort"
+	"sync"
+)
+
+// Index keeps the relationships between claims, the short claim IDs of live claims and the claims referencing
+// content hashes, fed by the events of a tracker. Relationships follow the current version of every claim: the
+// channel signing it, only when the signature is valid, the claim it reposts and the claims it lists when it is a
+// collection.
+type Index struct {
+	sync.Mutex
+	tracker     *Tracker
+	channels    map[string]map[string]bool
+	signedBy    map[string]string
+	reposts     map[string]map[string]bool
+	repostOf    map[string]string
+	collections map[string][]string
+	// every version of the claims referencing a stream descriptor or file hash
+	sdHashes     map[string][]ClaimVersion
+	sourceHashes map[string][]ClaimVersion
+	// live claims by claim ID and their claim IDs by normalized name and by channel and normalized name
+	live         map[string]indexedClaim
+	names        prefixIndex
+	channelNames prefixIndex
+}
+
+func NewIndex(tracker *Tracker) *Index {
+	i := &Index{
+		tracker:      tracker,
+		channels:     make(map[string]map[string]bool),
+		signedBy:     make(map[string]string),
+		reposts:      make(map[string]map[string]bool),
+		repostOf:     make(map[string]string),
+		collections:  make(map[string][]string),
+		sdHashes:     make(map[string][]ClaimVersion),
+		sourceHashes: make(map[string][]ClaimVersion),
+		live:         make(map[string]indexedClaim),
+		names:        make(prefixIndex),
+		channelNames: make(prefixIndex),
+	}
+	tracker.OnEvent(i.onEvent)
+	return i
+}
+
+// ChannelClaims returns the IDs of the claims signed by the channel, abandoned and expired ones included, sorted.
+func (i *Index) ChannelClaims(channelID string) []string {
+	i.Lock()
+	defer i.Unlock()
+	return sortedKeys(i.channels[channelID])
+}
+
+// Reposts returns the IDs of the claims reposting the claim, abandoned and expired ones included, sorted.
+func (i *Index) Reposts(claimID string) []string {
+	i.Lock()
+	defer i.Unlock()
+	return sortedKeys(i.reposts[claimID])
+}
+
+// CollectionClaims returns the IDs of the claims listed by the collection, in its order. They may not exist.
+func (i *Index) CollectionClaims(collectionID string) []string {
+	i.Lock()
+	defer i.Unlock()
+	return append([]string(nil), i.collections[collectionID]...)
+}
+
+func (i *Index) onEvent(event Event) {
+	claim, ok := i.tracker.tracked(event.ClaimID)
+	if !ok {
+		return
+	}
+	i.Lock()
+	defer i.Unlock()
+	switch event.Type {
+	case EventCreate, EventUpdate:
+		var channelID string
+		if claim.Output.IsSignatureValid {
+			channelID = claim.Output.SigningChannelID
+		}
+		i.setChannel(claim, channelID)
+		i.setRepost(claim)
+		i.setCollection(claim)
+		i.addSource(claim, event.Height)
+		i.removeLive(claim.ClaimID)
+		i.addLive(indexedClaim{claimID: claim.ClaimID, name: claim.Name, normalizedName: claim.NormalizedName, channelID: channelID, height: claim.Height})
+	case EventAbandon, EventExpire:
+		i.removeLive(claim.ClaimID)
+	}
+}
+
+func (i *Index) setChannel(claim *Claim, channelID string) {
+	link(i.channels, i.signedBy, claim.ClaimID, channelID)
+}
+
+func (i *Index) setRepost(claim *Claim) {
+	var target string
+	if hash := claim.Output.Claim.GetRepost().GetClaimHash(); len(hash) > 0 {
+		target = hex.EncodeToString(util.ReverseBytes(hash))
+	}
+	link(i.reposts, i.repostOf, claim.ClaimID, target)
+}
+
+func (i *Index) setCollection(claim *Claim) {
+	collection := claim.Output.Claim.GetCollection()
+	if collection == nil {
+		delete(i.collections, claim.ClaimID)
+		return
+	}
+	claimIDs := make([]string, 0, len(collection.GetClaimReferences()))
+	for _, reference := range collection.GetClaimReferences() {
+		claimIDs = append(claimIDs, hex.EncodeToString(util.ReverseBytes(reference.GetClaimHash())))
+	}
+	i.collections[claim.ClaimID] = claimIDs
+}
+
+// link moves the claim under the parent, or out of its previous parent when parent is empty.
+func link(children map[string]map[string]bool, parents map[string]string, claimID, parent string) {
+	if previous, ok := parents[claimID]; ok && previous != parent {
+		delete(children[previous], claimID)
+		delete(parents, claimID)
+	}
+	if parent == "" {
+		return
+	}
+	if _, ok := children[parent]; !ok {
+		children[parent] = make(map[string]bool)
+	}
+	children[parent][claimID] = true
+	parents[claimID] = parent
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package claims
+
+import (
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"fast-blocks/util"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func reference(claimID string) *pb.ClaimReference {
+	hash, _ := hex.DecodeString(claimID)
+	return &pb.ClaimReference{ClaimHash: util.ReverseBytes(hash)}
+}
+
+func TestIndexRelationships(t *testing.T) {
+	stream, channel, signed := strings.Repeat("aa", 20), strings.Repeat("cc", 20), strings.Repeat("bb", 20)
+	repost, collection := strings.Repeat("11", 20), strings.Repeat("22", 20)
+	missing := strings.Repeat("ff", 20)
+
+	repostOutput := func(op, target string) model.Output {
+		out := claimOutput(op, "repost", repost, 0, 1)
+		out.Claim = &pb.Claim{Type: &pb.Claim_Repost{Repost: reference(target)}}
+		return out
+	}
+	collectionOutput := claimOutput(lbrycrd.ClaimNameOp, "list", collection, 0, 1)
+	collectionOutput.Claim = &pb.Claim{Type: &pb.Claim_Collection{Collection: &pb.ClaimList{
+		ClaimReferences: []*pb.ClaimReference{reference(signed), reference(stream), reference(missing)},
+	}}}
+
+	tracker := NewTracker()
+	index := NewIndex(tracker)
+	blocks := []model.Block{
+		{Height: 1, Transactions: []model.Transaction{
+			{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "stream", stream, 0, 1)}},
+			{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "@channel", channel, 0, 1)}},
+			{Hash: "c", Outputs: []model.Output{publishedOutput("signed", signed, channel, 1)}},
+		}},
+		{Height: 2, Transactions: []model.Transaction{
+			{Hash: "d", Outputs: []model.Output{repostOutput(lbrycrd.ClaimNameOp, stream)}},
+			{Hash: "e", Outputs: []model.Output{collectionOutput}},
+		}},
+	}
+	for _, block := range blocks {
+		tracker.Apply(block)
+	}
+
+	if claims := index.ChannelClaims(channel); !reflect.DeepEqual(claims, []string{signed}) {
+		t.Errorf("unexpected channel claims %v", claims)
+	}
+	if claims := index.Reposts(stream); !reflect.DeepEqual(claims, []string{repost}) {
+		t.Errorf("unexpected reposts %v", claims)
+	}
+	if claims := index.CollectionClaims(collection); !reflect.DeepEqual(claims, []string{signed, stream, missing}) {
+		t.Errorf("unexpected collection claims %v", claims)
+	}
+
+	tracker.Apply(model.Block{Height: 3, Transactions: []model.Transaction{
+		{Hash: "f", Inputs: []model.Input{spend("d", 0)}, Outputs: []model.Output{repostOutput(lbrycrd.UpdateClaimOp, signed)}},
+	}})
+	if claims := index.Reposts(stream); len(claims) != 0 {
+		t.Errorf("expected the update to move the repost, got %v", claims)
+	}
+	if claims := index.Reposts(signed); !reflect.DeepEqual(claims, []string{repost}) {
+		t.Errorf("unexpected reposts %v", claims)
+	}
+}
@@ -0,0 +1,182 @@
+package claims
+
+import (
+	"fast-blocks/lbrycrd"
+	"sort"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/url"
+)
+
+// ErrClaimNotFound is returned when no claim matches a URL.
+var ErrClaimNotFound = errors.Base("claim not found")
+
+// segment is a name of a URL with its modifier: a claim ID prefix, a position in the order the claims of the name
+// were created in, or a position in the order of their effective amounts. Positions start at 1, 0 is unset.
+type segment struct {
+	name        string
+	claimID     string
+	sequence    int
+	amountOrder int
+}
+
+// Resolver resolves LBRY URLs against the claimtrie as of its last block.
+type Resolver struct {
+	trie    *ClaimTrie
+	index   *Index
+	amounts *SupportAggregator
+}
+
+func NewResolver(trie *ClaimTrie, index *Index, amounts *SupportAggregator) *Resolver {
+	return &Resolver{trie: trie, index: index, amounts: amounts}
+}
+
+// Resolve returns the claim a URL like lbry://@channel#a/stream#1 points to. Without a modifier a name resolves to
+// its controlling claim and a name within a channel to the claim of the channel with the largest effective amount.
+func (r *Resolver) Resolve(rawURL string) (*Claim, error) {
+	uri, err := url.Parse(rawURL, false)
+	if err != nil {
+		return nil, errors.Prefix("could not parse "+rawURL, err)
+	}
+	if uri.ChannelName == "" {
+		return r.resolveName(segment{uri.StreamName, uri.StreamClaimId, position(uri.PrimaryClaimSequence), position(uri.PrimaryBidPosition)})
+	}
+	channel, err := r.resolveName(segment{"@" + uri.ChannelName, uri.ChannelClaimId, position(uri.PrimaryClaimSequence), position(uri.PrimaryBidPosition)})
+	if err != nil || uri.IsChannel {
+		return channel, err
+	}
+	return r.resolveInChannel(channel, segment{uri.StreamName, uri.StreamClaimId, position(uri.SecondaryClaimSequence), position(uri.SecondaryBidPosition)})
+}
+
+func (r *Resolver) resolveName(s segment) (*Claim, error) {
+	state, ok := r.trie.NameAt(s.name, r.trie.Height())
+	if !ok {
+		return nil, errors.Err(ErrClaimNotFound)
+	}
+	var claimID string
+	switch {
+	case s.claimID != "":
+		var candidates []*Claim
+		for _, c := range state.Claims {
+			if strings.HasPrefix(c.ClaimID, s.claimID) {
+				candidates = append(candidates, r.claim(c.ClaimID))
+			}
+		}
+		if claim := pick(candidates, 1, byCreation); claim != nil {
+			claimID = claim.ClaimID
+		}
+	case s.sequence > 0:
+		candidates := make([]*Claim, 0, len(state.Claims))
+		for _, c := range state.Claims {
+			candidates = append(candidates, r.claim(c.ClaimID))
+		}
+		if claim := pick(candidates, s.sequence, byCreation); claim != nil {
+			claimID = claim.ClaimID
+		}
+	case s.amountOrder > 0:
+		// claims are ordered from the best, active ones first
+		if s.amountOrder <= len(state.Claims) && state.Claims[s.amountOrder-1].Active {
+			claimID = state.Claims[s.amountOrder-1].ClaimID
+		}
+	default:
+		claimID = state.Controlling
+	}
+	claim := r.claim(claimID)
+	if claim == nil {
+		return nil, errors.Err(ErrClaimNotFound)
+	}
+	return claim, nil
+}
+
+func (r *Resolver) resolveInChannel(channel *Claim, s segment) (*Claim, error) {
+	forks := lbrycrd.GetForks()
+	height := r.trie.Height()
+	name := forks.TrieName(s.name, height)
+	var candidates []*Claim
+	var claimIDs []string
+	for _, claimID := range r.index.ChannelClaims(channel.ClaimID) {
+		claim := r.claim(claimID)
+		if claim == nil || forks.TrieName(claim.Name, height) != name {
+			continue
+		}
+		if s.claimID == "" || strings.HasPrefix(claim.ClaimID, s.claimID) {
+			candidates = append(candidates, claim)
+			claimIDs = append(claimIDs, claim.ClaimID)
+		}
+	}
+	var claim *Claim
+	switch {
+	case s.claimID != "":
+		claim = pick(candidates, 1, byCreation)
+	case s.sequence > 0:
+		claim = pick(candidates, s.sequence, byCreation)
+	case s.amountOrder > 0:
+		claim = pick(candidates, s.amountOrder, byEffectiveAmount(r.amounts.EffectiveAmounts(claimIDs)))
+	default:
+		claim = pick(candidates, 1, byEffectiveAmount(r.amounts.EffectiveAmounts(claimIDs)))
+	}
+	if claim == nil {
+		return nil, errors.Err(ErrClaimNotFound)
+	}
+	return claim, nil
+}
+
+// EffectiveAmount returns the bid and the active supports of the claim as of the last block.
+func (r *Resolver) EffectiveAmount(claimID string) uint64 {
+	return r.amounts.EffectiveAmounts([]string{claimID})[claimID]
+}
+
+// Claim returns the claim if it is still live.
+func (r *Resolver) Claim(claimID string) (*Claim, bool) {
+	claim := r.claim(claimID)
+	return claim, claim != nil
+}
+
+func (r *Resolver) claim(claimID string) *Claim {
+	claim, ok := r.trie.Tracker().Claim(claimID)
+	if !ok || claim.Abandoned || claim.Expired {
+		return nil
+	}
+	return claim
+}
+
+func byCreation(a, b *Claim) bool {
+	if a.Height != b.Height {
+		return a.Height < b.Height
+	}
+	return a.ClaimID < b.ClaimID
+}
+
+// byEffectiveAmount orders claims by their effective amounts, the largest first.
+func byEffectiveAmount(amounts map[string]uint64) func(a, b *Claim) bool {
+	return func(a, b *Claim) bool {
+		if amountA, amountB := amounts[a.ClaimID], amounts[b.ClaimID]; amountA != amountB {
+			return amountA > amountB
+		}
+		return byCreation(a, b)
+	}
+}
+
+// pick returns the claim at the position, starting at 1, in the order given by less.
+func pick(claims []*Claim, position int, less func(a, b *Claim) bool) *Claim {
+	live := claims[:0]
+	for _, claim := range claims {
+		if claim != nil {
+			live = append(live, claim)
+		}
+	}
+	if position > len(live) {
+		return nil
+	}
+	sort.SliceStable(live, func(i, j int) bool { return less(live[i], live[j]) })
+	return live[position-1]
+}
+
+// position maps the unset modifiers of a parsed URL, -1, to 0.
+func position(p int) int {
+	if p < 0 {
+		return 0
+	}
+	return p
+}
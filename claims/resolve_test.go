@@ -0,0 +1,77 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func publishedOutput(name, claimID, channelID string, amount uint64) model.Output {
+	out := claimOutput(lbrycrd.ClaimNameOp, name, claimID, 0, amount)
+	out.SigningChannelID = channelID
+	out.IsSignatureValid = true
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	trie := NewClaimTrie()
+	resolver := NewResolver(trie, NewIndex(trie.Tracker()), NewSupportAggregator(trie))
+	blocks := []model.Block{
+		{Height: 1, Transactions: []model.Transaction{
+			{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "@chan", "c0ffee", 0, 1)}},
+			{Hash: "b", Outputs: []model.Output{publishedOutput("video", "aa11", "c0ffee", 5)}},
+		}},
+		{Height: 2, Transactions: []model.Transaction{
+			{Hash: "c", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "video", "bb22", 0, 10)}},
+		}},
+		{Height: 3, Transactions: []model.Transaction{
+			{Hash: "d", Outputs: []model.Output{publishedOutput("video", "aa33", "c0ffee", 1)}},
+		}},
+	}
+	for _, block := range blocks {
+		trie.Apply(block)
+	}
+
+	tests := map[string]string{
+		"lbry://video":             "bb22",
+		"video#aa":                 "aa11", // the oldest claim with the prefix
+		"video#aa3":                "aa33",
+		"video:1":                  "aa11",
+		"video:3":                  "aa33",
+		"video$1":                  "bb22",
+		"video$2":                  "aa11",
+		"lbry://@chan":             "c0ffee",
+		"lbry://@chan/video":       "aa11",
+		"lbry://@chan#c0/video:2":  "aa33",
+		"lbry://@chan/video$2":     "aa33",
+		"lbry://@chan/video#aa33":  "aa33",
+		"lbry://@chan:1/video#aa1": "aa11",
+	}
+	for url, expected := range tests {
+		claim, err := resolver.Resolve(url)
+		if err != nil {
+			t.Errorf("%s: %v", url, err)
+		} else if claim.ClaimID != expected {
+			t.Errorf("expected %s to resolve to %s, got %s", url, expected, claim.ClaimID)
+		}
+	}
+
+	for _, url := range []string{"missing", "video#ff", "video:4", "lbry://@chan/video#bb22", "lbry://@other/video"} {
+		if _, err := resolver.Resolve(url); !errors.Is(err, ErrClaimNotFound) {
+			t.Errorf("expected %s not to resolve, got %v", url, err)
+		}
+	}
+
+	// 98 blocks after the takeover of video, the support of aa33 waits 3 blocks before it counts
+	trie.Apply(model.Block{Height: 100, Transactions: []model.Transaction{
+		{Hash: "e", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "video", "aa33", 0, 10)}},
+	}})
+	for _, expected := range []string{"aa11", "aa11", "aa11", "aa33"} {
+		if claim, err := resolver.Resolve("lbry://@chan/video"); err != nil || claim.ClaimID != expected {
+			t.Errorf("expected the channel's video to resolve to %s at %d, got %+v %v", expected, trie.Height(), claim, err)
+		}
+		trie.Apply(model.Block{Height: trie.Height() + 1})
+	}
+}
@@ -0,0 +1,222 @@
+package claims
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	pb "github.com/lbryio/types/v2/go"
+)
+
+// Claim types of the search index.
+const (
+	ClaimTypeStream     = "stream"
+	ClaimTypeChannel    = "channel"
+	ClaimTypeCollection = "collection"
+	ClaimTypeRepost     = "repost"
+)
+
+// Orders of search results. Results come largest first, prefixed with ^ smallest first.
+const (
+	OrderByEffectiveAmount = "effective_amount"
+	OrderByHeight          = "height"
+)
+
+// SearchQuery filters claims. Every filter set must match: all the words of Text in the title or description, all
+// of AllTags, one of AnyTags, one of Languages, one of Locations, a country code, and one of ClaimTypes.
+type SearchQuery struct {
+	Text       string
+	AllTags    []string
+	AnyTags    []string
+	Languages  []string
+	Locations  []string
+	ClaimTypes []string
+	OrderBy    string
+}
+
+// SearchIndex is an inverted index over the metadata of live claims, fed by the events of a tracker. Effective
+// amounts come from the aggregator of the claimtrie the tracker belongs to.
+type SearchIndex struct {
+	sync.Mutex
+	tracker  *Tracker
+	amounts  *SupportAggregator
+	postings map[string]map[string]bool
+	terms    map[string][]string
+	heights  map[string]int
+}
+
+func NewSearchIndex(tracker *Tracker, amounts *SupportAggregator) *SearchIndex {
+	s := &SearchIndex{
+		tracker:  tracker,
+		amounts:  amounts,
+		postings: make(map[string]map[string]bool),
+		terms:    make(map[string][]string),
+		heights:  make(map[string]int),
+	}
+	tracker.OnEvent(s.onEvent)
+	return s
+}
+
+// Search returns the IDs of the claims matching the query, sorted by its order, the newest claims first by default.
+func (s *SearchIndex) Search(query SearchQuery) []string {
+	var filters [][]string
+	for _, word := range words(query.Text) {
+		filters = append(filters, []string{"word:" + word})
+	}
+	for _, tag := range query.AllTags {
+		filters = append(filters, []string{"tag:" + normalizeTag(tag)})
+	}
+	filters = appendAny(filters, "tag:", query.AnyTags, normalizeTag)
+	filters = appendAny(filters, "language:", query.Languages, strings.ToLower)
+	filters = appendAny(filters, "location:", query.Locations, strings.ToUpper)
+	filters = appendAny(filters, "type:", query.ClaimTypes, strings.ToLower)
+
+	orderBy := strings.TrimPrefix(query.OrderBy, "^")
+	s.Lock()
+	var claimIDs []string
+	if len(filters) == 0 {
+		for claimID := range s.terms {
+			claimIDs = append(claimIDs, claimID)
+		}
+	} else {
+		claimIDs = s.match(filters)
+	}
+	keys := make(map[string]uint64, len(claimIDs))
+	if orderBy != OrderByEffectiveAmount {
+		for _, claimID := range claimIDs {
+			keys[claimID] = uint64(s.heights[claimID])
+		}
+	}
+	s.Unlock()
+
+	if orderBy == OrderByEffectiveAmount {
+		keys = s.amounts.EffectiveAmounts(claimIDs)
+	}
+	ascending := strings.HasPrefix(query.OrderBy, "^")
+	sort.Slice(claimIDs, func(i, j int) bool {
+		a, b := claimIDs[i], claimIDs[j]
+		if keys[a] != keys[b] {
+			return (keys[a] < keys[b]) == ascending
+		}
+		return a < b
+	})
+	return claimIDs
+}
+
+// match returns the claims with one of the terms of every filter.
+func (s *SearchIndex) match(filters [][]string) []string {
+	var matches map[string]bool
+	for _, terms := range filters {
+		union := make(map[string]bool)
+		for _, term := range terms {
+			for claimID := range s.postings[term] {
+				if matches == nil || matches[claimID] {
+					union[claimID] = true
+				}
+			}
+		}
+		matches = union
+		if len(matches) == 0 {
+			return nil
+		}
+	}
+	claimIDs := make([]string, 0, len(matches))
+	for claimID := range matches {
+		claimIDs = append(claimIDs, claimID)
+	}
+	return claimIDs
+}
+
+func (s *SearchIndex) onEvent(event Event) {
+	claim, ok := s.tracker.tracked(event.ClaimID)
+	if !ok {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	switch event.Type {
+	case EventCreate, EventUpdate:
+		s.remove(claim.ClaimID)
+		s.add(claim.ClaimID, claimTerms(claim.Output.Claim))
+		s.heights[claim.ClaimID] = claim.Height
+	case EventAbandon, EventExpire:
+		s.remove(claim.ClaimID)
+	}
+}
+
+func (s *SearchIndex) add(claimID string, terms []string) {
+	for _, term := range terms {
+		if _, ok := s.postings[term]; !ok {
+			s.postings[term] = make(map[string]bool)
+		}
+		s.postings[term][claimID] = true
+	}
+	s.terms[claimID] = terms
+}
+
+func (s *SearchIndex) remove(claimID string) {
+	for _, term := range s.terms[claimID] {
+		delete(s.postings[term], claimID)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+	delete(s.terms, claimID)
+	delete(s.heights, claimID)
+}
+
+// claimTerms returns the terms a claim is found by, each prefixed by its kind.
+func claimTerms(claim *pb.Claim) []string {
+	unique := make(map[string]bool)
+	switch claim.GetType().(type) {
+	case *pb.Claim_Stream:
+		unique["type:"+ClaimTypeStream] = true
+	case *pb.Claim_Channel:
+		unique["type:"+ClaimTypeChannel] = true
+	case *pb.Claim_Collection:
+		unique["type:"+ClaimTypeCollection] = true
+	case *pb.Claim_Repost:
+		unique["type:"+ClaimTypeRepost] = true
+	}
+	for _, tag := range claim.GetTags() {
+		unique["tag:"+normalizeTag(tag)] = true
+	}
+	for _, language := range claim.GetLanguages() {
+		unique["language:"+strings.ToLower(language.GetLanguage().String())] = true
+	}
+	for _, location := range claim.GetLocations() {
+		unique["location:"+location.GetCountry().String()] = true
+	}
+	for _, word := range words(claim.GetTitle() + " " + claim.GetDescription()) {
+		unique["word:"+word] = true
+	}
+	terms := make([]string, 0, len(unique))
+	for term := range unique {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// words splits text into lowercase words of letters and digits.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func appendAny(filters [][]string, prefix string, values []string, normalize func(string) string) [][]string {
+	if len(values) == 0 {
+		return filters
+	}
+	terms := make([]string, len(values))
+	for i, value := range values {
+		terms[i] = prefix + normalize(value)
+	}
+	return append(filters, terms)
+}
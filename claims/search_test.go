@@ -0,0 +1,70 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"reflect"
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func valueOutput(name, claimID string, amount uint64, claim *pb.Claim) model.Output {
+	out := claimOutput(lbrycrd.ClaimNameOp, name, claimID, 0, amount)
+	out.Claim = claim
+	return out
+}
+
+func TestSearchIndex(t *testing.T) {
+	trie := NewClaimTrie()
+	index := NewSearchIndex(trie.Tracker(), NewSupportAggregator(trie))
+	blocks := []model.Block{
+		{Height: 1, Transactions: []model.Transaction{{Hash: "a", Outputs: []model.Output{valueOutput("concert", "s1", 10, &pb.Claim{
+			Type:      &pb.Claim_Stream{Stream: &pb.Stream{}},
+			Title:     "Live Concert in Paris",
+			Tags:      []string{"Music", "Live"},
+			Languages: []*pb.Language{{Language: pb.Language_en}},
+		})}}}},
+		{Height: 2, Transactions: []model.Transaction{{Hash: "b", Outputs: []model.Output{valueOutput("night", "s2", 20, &pb.Claim{
+			Type:        &pb.Claim_Stream{Stream: &pb.Stream{}},
+			Title:       "Night",
+			Description: "Paris, at night.",
+			Tags:        []string{"music"},
+			Languages:   []*pb.Language{{Language: pb.Language_fr}},
+			Locations:   []*pb.Location{{Country: pb.Location_FR}},
+		})}}}},
+		{Height: 3, Transactions: []model.Transaction{
+			{Hash: "c", Outputs: []model.Output{valueOutput("@music", "c1", 1, &pb.Claim{Type: &pb.Claim_Channel{Channel: &pb.Channel{}}, Tags: []string{"music"}})}},
+			{Hash: "d", Outputs: []model.Output{valueOutput("repost", "r1", 1, &pb.Claim{Type: &pb.Claim_Repost{Repost: &pb.ClaimReference{}}})}},
+		}},
+	}
+	for _, block := range blocks {
+		trie.Apply(block)
+	}
+
+	tests := []struct {
+		query    SearchQuery
+		expected []string
+	}{
+		{SearchQuery{Text: "PARIS"}, []string{"s2", "s1"}},
+		{SearchQuery{Text: "paris concert"}, []string{"s1"}},
+		{SearchQuery{AllTags: []string{"music", "live"}}, []string{"s1"}},
+		{SearchQuery{AnyTags: []string{"live", "MUSIC"}, ClaimTypes: []string{ClaimTypeStream}}, []string{"s2", "s1"}},
+		{SearchQuery{AnyTags: []string{"music"}, OrderBy: "^" + OrderByEffectiveAmount}, []string{"c1", "s1", "s2"}},
+		{SearchQuery{Languages: []string{"fr"}}, []string{"s2"}},
+		{SearchQuery{Locations: []string{"fr"}}, []string{"s2"}},
+		{SearchQuery{ClaimTypes: []string{ClaimTypeChannel, ClaimTypeRepost}, OrderBy: OrderByHeight}, []string{"c1", "r1"}},
+		{SearchQuery{Text: "paris", Languages: []string{"de"}}, nil},
+		{SearchQuery{}, []string{"c1", "r1", "s2", "s1"}},
+	}
+	for _, test := range tests {
+		if claimIDs := index.Search(test.query); !reflect.DeepEqual(claimIDs, test.expected) && len(claimIDs)+len(test.expected) > 0 {
+			t.Errorf("expected %+v to find %v, got %v", test.query, test.expected, claimIDs)
+		}
+	}
+
+	trie.Apply(model.Block{Height: 4, Transactions: []model.Transaction{{Hash: "e", Inputs: []model.Input{spend("b", 0)}}}})
+	if claimIDs := index.Search(SearchQuery{Text: "paris"}); !reflect.DeepEqual(claimIDs, []string{"s1"}) {
+		t.Errorf("expected the abandoned claim to leave the index, got %v", claimIDs)
+	}
+}
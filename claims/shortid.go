@@ -0,0 +1,130 @@
+package claims
+
+import (
+	"sort"
+)
+
+// indexedClaim is a live claim of the index.
+type indexedClaim struct {
+	claimID        string
+	name           string
+	normalizedName string
+	channelID      string
+	height         int
+}
+
+// older orders claims like the resolver does for a claim ID prefix: by creation height, then by claim ID.
+func (c indexedClaim) older(other indexedClaim) bool {
+	if c.height != other.height {
+		return c.height < other.height
+	}
+	return c.claimID < other.claimID
+}
+
+// prefixIndex keeps the claims of every group sorted by claim ID, so the claims sharing the longest prefix with a
+// claim are its neighbours.
+type prefixIndex map[string][]indexedClaim
+
+func (p prefixIndex) add(group string, claim indexedClaim) {
+	claims := p[group]
+	i := sort.Search(len(claims), func(i int) bool { return claims[i].claimID >= claim.claimID })
+	claims = append(claims, indexedClaim{})
+	copy(claims[i+1:], claims[i:])
+	claims[i] = claim
+	p[group] = claims
+}
+
+func (p prefixIndex) remove(group, claimID string) {
+	claims := p[group]
+	i := sort.Search(len(claims), func(i int) bool { return claims[i].claimID >= claimID })
+	if i == len(claims) || claims[i].claimID != claimID {
+		return
+	}
+	if len(claims) == 1 {
+		delete(p, group)
+		return
+	}
+	p[group] = append(claims[:i], claims[i+1:]...)
+}
+
+// shortID returns the shortest prefix of the claim ID no older claim of the group starts with. Moving away from the
+// claim in claim ID order the common prefix only shrinks, so the first older claim on each side is enough.
+func (p prefixIndex) shortID(group, claimID string) string {
+	claims := p[group]
+	i := sort.Search(len(claims), func(i int) bool { return claims[i].claimID >= claimID })
+	if i == len(claims) || claims[i].claimID != claimID {
+		return claimID
+	}
+	claim := claims[i]
+	length := 0
+	for j := i - 1; j >= 0; j-- {
+		if claims[j].older(claim) {
+			length = commonPrefix(claims[j].claimID, claimID)
+			break
+		}
+	}
+	for j := i + 1; j < len(claims); j++ {
+		if claims[j].older(claim) {
+			if common := commonPrefix(claims[j].claimID, claimID); common > length {
+				length = common
+			}
+			break
+		}
+	}
+	if length >= len(claimID) {
+		return claimID
+	}
+	return claimID[:length+1]
+}
+
+// ShortURL returns the URL of a live claim with the shortest claim ID prefix that resolves to it.
+func (i *Index) ShortURL(claimID string) (string, bool) {
+	i.Lock()
+	defer i.Unlock()
+	claim, ok := i.live[claimID]
+	if !ok {
+		return "", false
+	}
+	return i.shortURL(claim), true
+}
+
+// CanonicalURL returns the URL of a live claim within its channel, like lbry://@channel#x/stream#y, or its short URL
+// when it is not in a live channel. The stream's claim ID is shortened among the claims of the channel.
+func (i *Index) CanonicalURL(claimID string) (string, bool) {
+	i.Lock()
+	defer i.Unlock()
+	claim, ok := i.live[claimID]
+	if !ok {
+		return "", false
+	}
+	channel, ok := i.live[claim.channelID]
+	if !ok {
+		return i.shortURL(claim), true
+	}
+	group := claim.channelID + "/" + claim.normalizedName
+	return i.shortURL(channel) + "/" + claim.name + "#" + i.channelNames.shortID(group, claimID), true
+}
+
+func (i *Index) shortURL(claim indexedClaim) string {
+	return "lbry://" + claim.name + "#" + i.names.shortID(claim.normalizedName, claim.claimID)
+}
+
+func (i *Index) addLive(claim indexedClaim) {
+	i.live[claim.claimID] = claim
+	i.names.add(claim.normalizedName, claim)
+	if claim.channelID != "" {
+		i.channelNames.add(claim.channelID+"/"+claim.normalizedName, claim)
+	}
+}
+
+func (i *Index) removeLive(claimID string) {
+	claim, ok := i.live[claimID]
+	if !ok {
+		return
+	}
+	delete(i.live, claimID)
+	i.names.remove(claim.normalizedName, claimID)
+	if claim.channelID != "" {
+		i.channelNames.remove(claim.channelID+"/"+claim.normalizedName, claimID)
+	}
+}
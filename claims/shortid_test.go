@@ -0,0 +1,53 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"testing"
+)
+
+func TestShortURLs(t *testing.T) {
+	trie := NewClaimTrie()
+	index := NewIndex(trie.Tracker())
+	resolver := NewResolver(trie, index, NewSupportAggregator(trie))
+	blocks := []model.Block{
+		{Height: 1, Transactions: []model.Transaction{
+			{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "@chan", "c0ffee", 0, 1)}},
+			{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "video", "abc1", 0, 1)}},
+		}},
+		{Height: 2, Transactions: []model.Transaction{
+			{Hash: "c", Outputs: []model.Output{publishedOutput("Video", "abd2", "c0ffee", 1)}},
+		}},
+		{Height: 3, Transactions: []model.Transaction{
+			{Hash: "d", Outputs: []model.Output{publishedOutput("video", "abc3", "c0ffee", 1)}},
+		}},
+	}
+	for _, block := range blocks {
+		trie.Apply(block)
+	}
+
+	check := func(claimID, shortURL, canonicalURL string) {
+		if url, ok := index.ShortURL(claimID); !ok || url != shortURL {
+			t.Errorf("expected short URL %s for %s, got %s", shortURL, claimID, url)
+		}
+		if url, ok := index.CanonicalURL(claimID); !ok || url != canonicalURL {
+			t.Errorf("expected canonical URL %s for %s, got %s", canonicalURL, claimID, url)
+		}
+		for _, url := range []string{shortURL, canonicalURL} {
+			if claim, err := resolver.Resolve(url); err != nil || claim.ClaimID != claimID {
+				t.Errorf("expected %s to resolve to %s, got %v %v", url, claimID, claim, err)
+			}
+		}
+	}
+	check("c0ffee", "lbry://@chan#c", "lbry://@chan#c")
+	check("abc1", "lbry://video#a", "lbry://video#a")
+	check("abd2", "lbry://Video#abd", "lbry://@chan#c/Video#a")
+	check("abc3", "lbry://video#abc3", "lbry://@chan#c/video#abc")
+
+	trie.Apply(model.Block{Height: 4, Transactions: []model.Transaction{{Hash: "e", Inputs: []model.Input{spend("b", 0)}}}})
+	if _, ok := index.ShortURL("abc1"); ok {
+		t.Error("expected no short URL for an abandoned claim")
+	}
+	check("abd2", "lbry://Video#a", "lbry://@chan#c/Video#a")
+	check("abc3", "lbry://video#abc", "lbry://@chan#c/video#abc")
+}
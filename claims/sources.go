@@ -0,0 +1,43 @@
+package claims
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// ClaimVersion is a version of a claim, created by the output at Outpoint.
+type ClaimVersion struct {
+	ClaimID  string
+	Outpoint Outpoint
+	Height   int
+}
+
+// BySdHash returns every version of the claims whose stream has the hex encoded stream descriptor hash, oldest first.
+func (i *Index) BySdHash(sdHash string) []ClaimVersion {
+	i.Lock()
+	defer i.Unlock()
+	return append([]ClaimVersion(nil), i.sdHashes[strings.ToLower(sdHash)]...)
+}
+
+// BySourceHash returns every version of the claims whose stream has the hex encoded file hash, oldest first.
+func (i *Index) BySourceHash(hash string) []ClaimVersion {
+	i.Lock()
+	defer i.Unlock()
+	return append([]ClaimVersion(nil), i.sourceHashes[strings.ToLower(hash)]...)
+}
+
+func (i *Index) addSource(claim *Claim, height int) {
+	source := claim.Output.Claim.GetStream().GetSource()
+	if source == nil {
+		return
+	}
+	version := ClaimVersion{ClaimID: claim.ClaimID, Outpoint: claim.Outpoint, Height: height}
+	if len(source.GetSdHash()) > 0 {
+		sdHash := hex.EncodeToString(source.GetSdHash())
+		i.sdHashes[sdHash] = append(i.sdHashes[sdHash], version)
+	}
+	if len(source.GetHash()) > 0 {
+		hash := hex.EncodeToString(source.GetHash())
+		i.sourceHashes[hash] = append(i.sourceHashes[hash], version)
+	}
+}
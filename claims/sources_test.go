@@ -0,0 +1,48 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"reflect"
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func streamClaim(sdHash, hash []byte) *pb.Claim {
+	return &pb.Claim{Type: &pb.Claim_Stream{Stream: &pb.Stream{Source: &pb.Source{SdHash: sdHash, Hash: hash}}}}
+}
+
+func TestIndexSources(t *testing.T) {
+	tracker := NewTracker()
+	index := NewIndex(tracker)
+	sdHash, hash := []byte{0xab, 0xcd}, []byte{0x12, 0x34}
+
+	update := claimOutput(lbrycrd.UpdateClaimOp, "stream", "c1", 0, 1)
+	update.Claim = streamClaim(sdHash, []byte{0x56})
+	tracker.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: "a", Outputs: []model.Output{valueOutput("stream", "c1", 1, streamClaim(sdHash, hash))}},
+		{Hash: "b", Outputs: []model.Output{valueOutput("other", "c2", 1, streamClaim([]byte{0xef}, hash))}},
+	}})
+	tracker.Apply(model.Block{Height: 2, Transactions: []model.Transaction{
+		{Hash: "c", Inputs: []model.Input{spend("a", 0)}, Outputs: []model.Output{update}},
+	}})
+
+	expected := []ClaimVersion{
+		{ClaimID: "c1", Outpoint: Outpoint{TxID: "a"}, Height: 1},
+		{ClaimID: "c1", Outpoint: Outpoint{TxID: "c"}, Height: 2},
+	}
+	if versions := index.BySdHash("ABCD"); !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %+v, got %+v", expected, versions)
+	}
+	expected = []ClaimVersion{
+		{ClaimID: "c1", Outpoint: Outpoint{TxID: "a"}, Height: 1},
+		{ClaimID: "c2", Outpoint: Outpoint{TxID: "b"}, Height: 1},
+	}
+	if versions := index.BySourceHash("1234"); !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %+v, got %+v", expected, versions)
+	}
+	if versions := index.BySdHash("00"); len(versions) != 0 {
+		t.Errorf("expected no claims, got %+v", versions)
+	}
+}
@@ -0,0 +1,163 @@
+package claims
+
+import (
+	"sort"
+	"sync"
+)
+
+// Amounts are the bid of a claim and its active supports at a height. Supports from the address holding the claim
+// count as OwnerSupports, the ones from other addresses as Tips. A claim leaving the claimtrie has no amounts.
+type Amounts struct {
+	ClaimID       string
+	Name          string
+	Height        int
+	Bid           uint64
+	OwnerSupports uint64
+	Tips          uint64
+}
+
+// EffectiveAmount returns the bid and the active supports of the claim.
+func (a Amounts) EffectiveAmount() uint64 {
+	return a.Bid + a.OwnerSupports + a.Tips
+}
+
+func (a Amounts) equal(other Amounts) bool {
+	return a.Name == other.Name && a.Bid == other.Bid && a.OwnerSupports == other.OwnerSupports && a.Tips == other.Tips
+}
+
+// SupportAggregator keeps the amounts of every claim of a claimtrie over time. They are recomputed for the names
+// changed by each block, so supports count from the height they activate at.
+type SupportAggregator struct {
+	sync.Mutex
+	history     map[string][]Amounts
+	byName      map[string]map[string]bool
+	onChangeFns []func(amounts Amounts)
+}
+
+func NewSupportAggregator(trie *ClaimTrie) *SupportAggregator {
+	s := &SupportAggregator{
+		history: make(map[string][]Amounts),
+		byName:  make(map[string]map[string]bool),
+	}
+	trie.Lock()
+	trie.onApplyFns = append(trie.onApplyFns, s.apply)
+	trie.Unlock()
+	return s
+}
+
+// OnChange adds a function called with the new amounts of a claim, in chain order. It is called at most once per claim
+// and block, with the amounts the claim has once the block is applied.
+func (s *SupportAggregator) OnChange(fn func(amounts Amounts)) {
+	s.onChangeFns = append(s.onChangeFns, fn)
+}
+
+// AmountsAt returns the amounts of the claim as of the height.
+func (s *SupportAggregator) AmountsAt(claimID string, height int) (Amounts, bool) {
+	s.Lock()
+	defer s.Unlock()
+	history := s.history[claimID]
+	i := sort.Search(len(history), func(i int) bool { return history[i].Height > height })
+	if i == 0 {
+		return Amounts{}, false
+	}
+	return history[i-1], true
+}
+
+// EffectiveAmounts returns the effective amount of the claims as of the last block, 0 for claims not in the
+// claimtrie. It is the one definition of an effective amount, the bid and the active supports.
+func (s *SupportAggregator) EffectiveAmounts(claimIDs []string) map[string]uint64 {
+	s.Lock()
+	defer s.Unlock()
+	amounts := make(map[string]uint64, len(claimIDs))
+	for _, claimID := range claimIDs {
+		if history := s.history[claimID]; len(history) > 0 {
+			amounts[claimID] = history[len(history)-1].EffectiveAmount()
+		}
+	}
+	return amounts
+}
+
+// apply runs within ClaimTrie.Apply with the names changed by the block.
+func (s *SupportAggregator) apply(height int, changed []*trieNode) {
+	s.Lock()
+	defer s.Unlock()
+	seen := make(map[string]bool)
+	var current []Amounts
+	for _, n := range changed {
+		for _, amounts := range n.amounts(height) {
+			seen[amounts.ClaimID] = true
+			current = append(current, amounts)
+		}
+	}
+	// a claim moving between names is set more than once, it is reported once with its final amounts
+	updated := make(map[string]bool)
+	var order []string
+	update := func(amounts Amounts) {
+		if s.set(amounts) && !updated[amounts.ClaimID] {
+			updated[amounts.ClaimID] = true
+			order = append(order, amounts.ClaimID)
+		}
+	}
+	for _, n := range changed {
+		for claimID := range s.byName[n.name] {
+			if !seen[claimID] {
+				update(Amounts{ClaimID: claimID, Height: height})
+			}
+		}
+		delete(s.byName, n.name)
+	}
+	for _, amounts := range current {
+		if _, ok := s.byName[amounts.Name]; !ok {
+			s.byName[amounts.Name] = make(map[string]bool)
+		}
+		s.byName[amounts.Name][amounts.ClaimID] = true
+		update(amounts)
+	}
+	for _, claimID := range order {
+		history := s.history[claimID]
+		for _, fn := range s.onChangeFns {
+			fn(history[len(history)-1])
+		}
+	}
+}
+
+// set records the amounts of a claim, replacing the ones set earlier at the same height. It returns false if they
+// did not change.
+func (s *SupportAggregator) set(amounts Amounts) bool {
+	history := s.history[amounts.ClaimID]
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.equal(amounts) {
+			return false
+		}
+		if last.Height == amounts.Height {
+			history = history[:len(history)-1]
+		}
+	} else if amounts.Name == "" {
+		return false
+	}
+	s.history[amounts.ClaimID] = append(history, amounts)
+	return true
+}
+
+// amounts returns the amounts of the claims of the node at the height.
+func (n *trieNode) amounts(height int) []Amounts {
+	result := make([]Amounts, 0, len(n.liveClaims))
+	claims := make(map[string]int, len(n.liveClaims))
+	for _, claim := range n.liveClaims {
+		claims[claim.ClaimID] = len(result)
+		result = append(result, Amounts{ClaimID: claim.ClaimID, Name: n.name, Height: height, Bid: claim.Amount})
+	}
+	for _, support := range n.liveSupports {
+		i, ok := claims[support.ClaimID]
+		if !ok || support.ActiveAt > height {
+			continue
+		}
+		if support.Address != "" && support.Address == n.liveClaims[i].Address {
+			result[i].OwnerSupports += support.Amount
+		} else {
+			result[i].Tips += support.Amount
+		}
+	}
+	return result
+}
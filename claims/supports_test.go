@@ -0,0 +1,82 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"testing"
+)
+
+func addressed(out model.Output, address string) model.Output {
+	out.Address = model.Address{Encoded: address}
+	return out
+}
+
+func TestSupportAggregator(t *testing.T) {
+	trie := NewClaimTrie()
+	aggregator := NewSupportAggregator(trie)
+	var changes []Amounts
+	aggregator.OnChange(func(amounts Amounts) {
+		changes = append(changes, amounts)
+	})
+
+	blocks := map[int][]model.Transaction{
+		1: {{Hash: "a", Outputs: []model.Output{addressed(claimOutput(lbrycrd.ClaimNameOp, "name", "ca", 0, 10), "owner")}}},
+		2: {{Hash: "b", Outputs: []model.Output{
+			addressed(claimOutput(lbrycrd.SupportClaimOp, "name", "ca", 0, 5), "owner"),
+			addressed(claimOutput(lbrycrd.SupportClaimOp, "name", "ca", 1, 3), "fan"),
+		}}},
+		3: {{Hash: "c", Outputs: []model.Output{addressed(claimOutput(lbrycrd.ClaimNameOp, "name", "cb", 0, 1), "other")}}},
+		// 64 blocks after the takeover of ca, the tip waits 2 blocks
+		65: {{Hash: "d", Outputs: []model.Output{addressed(claimOutput(lbrycrd.SupportClaimOp, "name", "cb", 0, 2), "fan")}}},
+		70: {{Hash: "e", Inputs: []model.Input{spend("c", 0)}}},
+	}
+	for height := 1; height <= 70; height++ {
+		trie.Apply(model.Block{Height: height, Transactions: blocks[height]})
+	}
+
+	tests := []struct {
+		claimID  string
+		height   int
+		expected Amounts
+	}{
+		{"ca", 1, Amounts{ClaimID: "ca", Name: "name", Height: 1, Bid: 10}},
+		{"ca", 70, Amounts{ClaimID: "ca", Name: "name", Height: 2, Bid: 10, OwnerSupports: 5, Tips: 3}},
+		{"cb", 66, Amounts{ClaimID: "cb", Name: "name", Height: 3, Bid: 1}},
+		{"cb", 67, Amounts{ClaimID: "cb", Name: "name", Height: 67, Bid: 1, Tips: 2}},
+		{"cb", 70, Amounts{ClaimID: "cb", Height: 70}},
+	}
+	for _, test := range tests {
+		if amounts, ok := aggregator.AmountsAt(test.claimID, test.height); !ok || amounts != test.expected {
+			t.Errorf("expected %+v at %d, got %+v", test.expected, test.height, amounts)
+		}
+	}
+	if _, ok := aggregator.AmountsAt("cb", 2); ok {
+		t.Error("expected no amounts before the claim")
+	}
+	if len(changes) != 5 {
+		t.Errorf("expected 5 changes, got %+v", changes)
+	}
+	if amounts, _ := aggregator.AmountsAt("ca", 70); amounts.EffectiveAmount() != 18 {
+		t.Errorf("unexpected effective amount %d", amounts.EffectiveAmount())
+	}
+}
+
+func TestSupportAggregatorChangesOncePerBlock(t *testing.T) {
+	aggregator := NewSupportAggregator(NewClaimTrie())
+	var changes []Amounts
+	aggregator.OnChange(func(amounts Amounts) {
+		changes = append(changes, amounts)
+	})
+	// the claim is set twice while the block is applied, replacing its amounts at the height
+	first := &trieNode{name: "first", liveClaims: []*TrieClaim{{ClaimID: "ca", Amount: 1}}}
+	second := &trieNode{name: "second", liveClaims: []*TrieClaim{{ClaimID: "ca", Amount: 2}}}
+	aggregator.apply(5, []*trieNode{first, second})
+
+	expected := Amounts{ClaimID: "ca", Name: "second", Height: 5, Bid: 2}
+	if len(changes) != 1 || changes[0] != expected {
+		t.Errorf("expected one change %+v, got %+v", expected, changes)
+	}
+	if amounts, _ := aggregator.AmountsAt("ca", 5); amounts != expected {
+		t.Errorf("expected %+v, got %+v", expected, amounts)
+	}
+}
@@ -0,0 +1,290 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"sync"
+)
+
+// Event types of a claim's lifecycle.
+const (
+	EventCreate        = "create"
+	EventUpdate        = "update"
+	EventAbandon       = "abandon"
+	EventSupport       = "support"
+	EventSupportRemove = "support_remove"
+	EventExpire        = "expire"
+)
+
+// Event is a change to a claim at a height. TxID and Vout are the outpoint created by a create, update or support
+// and the outpoint spent by an abandon or support removal, Address the address holding it.
+type Event struct {
+	Type           string
+	ClaimID        string
+	Name           string
+	NormalizedName string
+	Height         int
+	TxID           string
+	Vout           uint32
+	Amount         uint64
+	Address        string
+}
+
+// Outpoint identifies a transaction output.
+type Outpoint struct {
+	TxID string
+	Vout uint32
+}
+
+// Claim is the current version of a claim along with its live supports.
+type Claim struct {
+	ClaimID string
+	// Name is the name as it was claimed, NormalizedName the form lbrycrd compares from the NormalizedName fork on.
+	Name           string
+	NormalizedName string
+	Outpoint       Outpoint
+	Amount         uint64
+	// Height is where the claim was created, UpdateHeight where its current version was.
+	Height       int
+	UpdateHeight int
+	// ExpirationHeight is the first height the current version is no longer part of the claimtrie.
+	ExpirationHeight int
+	Output           model.Output
+	Supports         map[Outpoint]*Support
+	// Abandoned and Expired claims stay known to the tracker, their outpoint is no longer live.
+	Abandoned bool
+	Expired   bool
+	History   []Event
+}
+
+// ExpiredAt returns true if the current version of the claim is expired at the height.
+func (c *Claim) ExpiredAt(height int) bool {
+	return height >= c.ExpirationHeight
+}
+
+// copy returns the claim with its own supports and history.
+func (c *Claim) copy() *Claim {
+	claim := *c
+	claim.Supports = make(map[Outpoint]*Support, len(c.Supports))
+	for outpoint, support := range c.Supports {
+		claim.Supports[outpoint] = support
+	}
+	claim.History = append([]Event(nil), c.History...)
+	return &claim
+}
+
+// Support is a live support of a claim.
+type Support struct {
+	ClaimID          string
+	Name             string
+	NormalizedName   string
+	Outpoint         Outpoint
+	Amount           uint64
+	Address          string
+	Height           int
+	ExpirationHeight int
+}
+
+// Tracker follows every claim and support through the blocks of the best chain. Blocks must be applied in height
+// order, typically from Chain.OnBlock.
+type Tracker struct {
+	sync.Mutex
+	claims   map[string]*Claim
+	live     map[Outpoint]*Claim
+	supports map[Outpoint]*Support
+	// pending holds the live supports of claim IDs not created yet by claim ID, lbrycrd accepts them
+	pending     map[string]map[Outpoint]*Support
+	expirations map[int][]Outpoint
+	onEventFns  []func(event Event)
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		claims:      make(map[string]*Claim),
+		live:        make(map[Outpoint]*Claim),
+		supports:    make(map[Outpoint]*Support),
+		pending:     make(map[string]map[Outpoint]*Support),
+		expirations: make(map[int][]Outpoint),
+	}
+}
+
+// OnEvent adds a function called for every lifecycle event, in chain order.
+func (t *Tracker) OnEvent(fn func(event Event)) {
+	t.onEventFns = append(t.onEventFns, fn)
+}
+
+// Claim returns a copy of the current state of a claim, which blocks applied later leave untouched.
+func (t *Tracker) Claim(claimID string) (*Claim, bool) {
+	t.Lock()
+	defer t.Unlock()
+	claim, ok := t.claims[claimID]
+	if !ok {
+		return nil, false
+	}
+	return claim.copy(), true
+}
+
+// tracked returns the claim the tracker holds, not a copy. It is meant for the functions added with OnEvent: they run
+// within Apply, which holds the lock, and must not keep the claim.
+func (t *Tracker) tracked(claimID string) (*Claim, bool) {
+	claim, ok := t.claims[claimID]
+	return claim, ok
+}
+
+// History returns every event of a claim, oldest first.
+func (t *Tracker) History(claimID string) []Event {
+	t.Lock()
+	defer t.Unlock()
+	claim, ok := t.claims[claimID]
+	if !ok {
+		return nil
+	}
+	return append([]Event(nil), claim.History...)
+}
+
+// Apply processes the block. Claims and supports expiring at its height are expired before its transactions run.
+func (t *Tracker) Apply(block model.Block) {
+	t.Lock()
+	defer t.Unlock()
+	t.expire(block.Height)
+	for _, tx := range block.Transactions {
+		t.applyTransaction(tx, block.Height)
+	}
+}
+
+func (t *Tracker) applyTransaction(tx model.Transaction, height int) {
+	spent := make(map[string]*Claim)
+	var spentOrder []*Claim
+	for _, in := range tx.Inputs {
+		outpoint := Outpoint{TxID: in.TxRef, Vout: in.Position}
+		if claim, ok := t.live[outpoint]; ok {
+			delete(t.live, outpoint)
+			spent[claim.ClaimID] = claim
+			spentOrder = append(spentOrder, claim)
+		}
+		if support, ok := t.supports[outpoint]; ok {
+			t.removeSupport(support)
+			t.emit(supportEvent(EventSupportRemove, support, height, outpoint))
+		}
+	}
+
+	for _, out := range tx.Outputs {
+		outpoint := Outpoint{TxID: tx.Hash, Vout: out.Vout}
+		switch out.ClaimOperation {
+		case lbrycrd.ClaimNameOp:
+			claim := &Claim{ClaimID: out.ClaimID, Height: height, Supports: make(map[Outpoint]*Support)}
+			if existing, ok := t.claims[out.ClaimID]; ok {
+				claim = existing // a claim ID can only be reused by replaying the same outpoint
+			}
+			for supportOutpoint, support := range t.pending[out.ClaimID] {
+				claim.Supports[supportOutpoint] = support
+			}
+			delete(t.pending, out.ClaimID)
+			t.claims[out.ClaimID] = claim
+			t.setVersion(claim, out, outpoint, height)
+			t.emit(claimEvent(EventCreate, claim, height, outpoint))
+		case lbrycrd.UpdateClaimOp:
+			// lbrycrd only accepts an update spending the current version of the claim under the same name, compared
+			// in normalized form from the NormalizedName fork on
+			claim, ok := spent[out.ClaimID]
+			forks := lbrycrd.GetForks()
+			if !ok || forks.TrieName(claim.Name, height) != forks.TrieName(out.ClaimName, height) {
+				continue
+			}
+			delete(spent, out.ClaimID)
+			t.setVersion(claim, out, outpoint, height)
+			t.emit(claimEvent(EventUpdate, claim, height, outpoint))
+		case lbrycrd.SupportClaimOp:
+			support := &Support{ClaimID: out.ClaimID, Name: out.ClaimName, NormalizedName: lbrycrd.NormalizeName(out.ClaimName), Outpoint: outpoint, Amount: out.Amount, Address: out.Address.Encoded, Height: height, ExpirationHeight: lbrycrd.GetForks().ExpirationHeight(height)}
+			t.supports[outpoint] = support
+			t.expirations[support.ExpirationHeight] = append(t.expirations[support.ExpirationHeight], outpoint)
+			if claim, ok := t.claims[out.ClaimID]; ok {
+				claim.Supports[outpoint] = support
+			} else {
+				if t.pending[out.ClaimID] == nil {
+					t.pending[out.ClaimID] = make(map[Outpoint]*Support)
+				}
+				t.pending[out.ClaimID][outpoint] = support
+			}
+			t.emit(supportEvent(EventSupport, support, height, outpoint))
+		}
+	}
+
+	for _, claim := range spentOrder {
+		if _, ok := spent[claim.ClaimID]; !ok {
+			continue // updated
+		}
+		claim.Abandoned = true
+		t.emit(claimEvent(EventAbandon, claim, height, claim.Outpoint))
+	}
+}
+
+// setVersion makes the output the current version of the claim.
+func (t *Tracker) setVersion(claim *Claim, out model.Output, outpoint Outpoint, height int) {
+	claim.Name = out.ClaimName
+	claim.NormalizedName = lbrycrd.NormalizeName(out.ClaimName)
+	claim.Outpoint = outpoint
+	claim.Amount = out.Amount
+	claim.Output = out
+	claim.UpdateHeight = height
+	claim.ExpirationHeight = lbrycrd.GetForks().ExpirationHeight(height)
+	claim.Abandoned = false
+	claim.Expired = false
+	t.live[outpoint] = claim
+	t.expirations[claim.ExpirationHeight] = append(t.expirations[claim.ExpirationHeight], outpoint)
+}
+
+// removeSupport drops a support that was spent or expired from its claim, or from the pending ones if its claim
+// does not exist.
+func (t *Tracker) removeSupport(support *Support) {
+	delete(t.supports, support.Outpoint)
+	if claim, ok := t.claims[support.ClaimID]; ok {
+		delete(claim.Supports, support.Outpoint)
+		return
+	}
+	delete(t.pending[support.ClaimID], support.Outpoint)
+	if len(t.pending[support.ClaimID]) == 0 {
+		delete(t.pending, support.ClaimID)
+	}
+}
+
+// expire removes the claims and supports whose current version expires at the height.
+func (t *Tracker) expire(height int) {
+	outpoints := t.expirations[height]
+	delete(t.expirations, height)
+	for _, outpoint := range outpoints {
+		if claim, ok := t.live[outpoint]; ok && claim.ExpirationHeight == height {
+			delete(t.live, outpoint)
+			claim.Expired = true
+			t.emit(claimEvent(EventExpire, claim, height, outpoint))
+		}
+		if support, ok := t.supports[outpoint]; ok && support.ExpirationHeight == height {
+			t.removeSupport(support)
+			t.emit(supportEvent(EventSupportRemove, support, height, outpoint))
+		}
+	}
+}
+
+// ExpiredAt returns true if the support is expired at the height.
+func (s *Support) ExpiredAt(height int) bool {
+	return height >= s.ExpirationHeight
+}
+
+func claimEvent(eventType string, claim *Claim, height int, outpoint Outpoint) Event {
+	return Event{Type: eventType, ClaimID: claim.ClaimID, Name: claim.Name, NormalizedName: claim.NormalizedName, Height: height,
+		TxID: outpoint.TxID, Vout: outpoint.Vout, Amount: claim.Amount, Address: claim.Output.Address.Encoded}
+}
+
+func supportEvent(eventType string, support *Support, height int, outpoint Outpoint) Event {
+	return Event{Type: eventType, ClaimID: support.ClaimID, Name: support.Name, NormalizedName: support.NormalizedName, Height: height,
+		TxID: outpoint.TxID, Vout: outpoint.Vout, Amount: support.Amount, Address: support.Address}
+}
+
+func (t *Tracker) emit(event Event) {
+	if claim, ok := t.claims[event.ClaimID]; ok {
+		claim.History = append(claim.History, event)
+	}
+	for _, fn := range t.onEventFns {
+		fn(event)
+	}
+}
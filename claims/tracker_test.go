@@ -0,0 +1,125 @@
+package claims
+
+import (
+	"fast-blocks/blockchain/model"
+	"fast-blocks/lbrycrd"
+	"testing"
+)
+
+func claimOutput(op, name, claimID string, vout uint32, amount uint64) model.Output {
+	return model.Output{ClaimOperation: op, ClaimName: name, ClaimID: claimID, Vout: vout, Amount: amount}
+}
+
+func spend(txID string, vout uint32) model.Input {
+	return model.Input{TxRef: txID, Position: vout}
+}
+
+func TestTrackerLifecycle(t *testing.T) {
+	tracker := NewTracker()
+	var events []Event
+	tracker.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+
+	blocks := []model.Block{
+		{Height: 1, Transactions: []model.Transaction{
+			{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "one", "c1", 0, 10)}},
+			{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "two", "c2", 0, 5)}},
+		}},
+		{Height: 2, Transactions: []model.Transaction{
+			{Hash: "c", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "one", "c1", 0, 3)}},
+			// updates that do not spend the claim are ignored
+			{Hash: "d", Outputs: []model.Output{claimOutput(lbrycrd.UpdateClaimOp, "one", "c1", 0, 1)}},
+		}},
+		{Height: 3, Transactions: []model.Transaction{
+			{Hash: "e", Inputs: []model.Input{spend("a", 0)}, Outputs: []model.Output{claimOutput(lbrycrd.UpdateClaimOp, "one", "c1", 1, 20)}},
+		}},
+		{Height: 4, Transactions: []model.Transaction{
+			{Hash: "f", Inputs: []model.Input{spend("c", 0), spend("e", 1)}},
+		}},
+	}
+	for _, block := range blocks {
+		tracker.Apply(block)
+	}
+
+	expected := []string{EventCreate, EventCreate, EventSupport, EventUpdate, EventSupportRemove, EventAbandon}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("event %d: expected %s, got %+v", i, expected[i], event)
+		}
+	}
+	if events[3].Height != 3 || events[3].TxID != "e" || events[3].Vout != 1 || events[3].Amount != 20 {
+		t.Errorf("unexpected update %+v", events[3])
+	}
+
+	claim, ok := tracker.Claim("c1")
+	if !ok || !claim.Abandoned || claim.Height != 1 || claim.UpdateHeight != 3 || len(claim.Supports) != 0 {
+		t.Errorf("unexpected claim %+v", claim)
+	}
+	if history := tracker.History("c1"); len(history) != 5 {
+		t.Errorf("expected 5 events for c1, got %+v", history)
+	}
+
+	expiration := lbrycrd.GetForks().ExpirationHeight(1)
+	if claim, _ := tracker.Claim("c2"); claim.ExpirationHeight != expiration || claim.ExpiredAt(expiration-1) || !claim.ExpiredAt(expiration) {
+		t.Errorf("unexpected expiration of %+v", claim)
+	}
+	tracker.Apply(model.Block{Height: expiration})
+	if claim, _ := tracker.Claim("c2"); !claim.Expired {
+		t.Errorf("expected c2 to expire, got %+v", claim)
+	}
+	if last := events[len(events)-1]; last.Type != EventExpire || last.ClaimID != "c2" {
+		t.Errorf("unexpected last event %+v", last)
+	}
+}
+
+func TestTrackerClaimCopy(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: "a", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "one", "c1", 0, 10)}},
+	}})
+	claim, _ := tracker.Claim("c1")
+	tracker.Apply(model.Block{Height: 2, Transactions: []model.Transaction{
+		{Hash: "b", Outputs: []model.Output{claimOutput(lbrycrd.SupportClaimOp, "one", "c1", 0, 3)}},
+		{Hash: "c", Inputs: []model.Input{spend("a", 0)}, Outputs: []model.Output{claimOutput(lbrycrd.UpdateClaimOp, "one", "c1", 0, 20)}},
+	}})
+	if claim.Amount != 10 || len(claim.Supports) != 0 || len(claim.History) != 1 {
+		t.Errorf("expected the claim as of height 1, got %+v", claim)
+	}
+	if claim, _ := tracker.Claim("c1"); claim.Amount != 20 || len(claim.Supports) != 1 || len(claim.History) != 3 {
+		t.Errorf("expected the claim as of height 2, got %+v", claim)
+	}
+}
+
+func TestTrackerSupportBeforeClaim(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: "a", Outputs: []model.Output{
+			claimOutput(lbrycrd.SupportClaimOp, "one", "c1", 0, 3),
+			claimOutput(lbrycrd.SupportClaimOp, "one", "c1", 1, 4),
+		}},
+	}})
+	// the second support is spent before its claim exists
+	tracker.Apply(model.Block{Height: 2, Transactions: []model.Transaction{
+		{Hash: "b", Inputs: []model.Input{spend("a", 1)}},
+	}})
+	tracker.Apply(model.Block{Height: 3, Transactions: []model.Transaction{
+		{Hash: "c", Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "one", "c1", 0, 10)}},
+	}})
+	claim, ok := tracker.Claim("c1")
+	if !ok || len(claim.Supports) != 1 || claim.Supports[Outpoint{TxID: "a", Vout: 0}].Amount != 3 {
+		t.Fatalf("expected the live support made before the claim, got %+v", claim)
+	}
+	if len(tracker.pending) != 0 {
+		t.Errorf("expected no pending supports once the claim exists, got %+v", tracker.pending)
+	}
+	tracker.Apply(model.Block{Height: 4, Transactions: []model.Transaction{
+		{Hash: "d", Inputs: []model.Input{spend("a", 0)}},
+	}})
+	if claim, _ := tracker.Claim("c1"); len(claim.Supports) != 0 {
+		t.Errorf("expected the support to be removed, got %+v", claim.Supports)
+	}
+}
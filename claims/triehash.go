@@ -0,0 +1,246 @@
+package claims
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fast-blocks/util"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Hashes lbrycrd uses in place of an empty claimtrie, a node without children and a node without claims.
+var (
+	EmptyTrieHash  = chainhash.Hash{1}
+	NoChildrenHash = chainhash.Hash{2}
+	NoClaimsHash   = chainhash.Hash{3}
+)
+
+// RootError reports a block whose claimtrie root differs from the root of the replayed claimtrie.
+type RootError struct {
+	Height    int
+	BlockHash string
+	Expected  string
+	Computed  string
+}
+
+func (e *RootError) Error() string {
+	return fmt.Sprintf("block %s at height %d has claimtrie root %s but the claimtrie hashes to %s", e.BlockHash, e.Height, e.Expected, e.Computed)
+}
+
+// hashNode is a node of the compressed trie of names used to hash the claimtrie. Each node caches the hash of its
+// subtree until a name below it changes.
+type hashNode struct {
+	key      string
+	children []*hashNode
+	node     *trieNode
+	hash     chainhash.Hash
+	present  bool
+	valid    bool
+}
+
+type hashTrie struct {
+	root      *hashNode
+	allClaims bool
+}
+
+func newHashTrie() *hashTrie {
+	return &hashTrie{root: &hashNode{}}
+}
+
+// touch adds the name to the trie if needed and drops the cached hashes on its path.
+func (t *hashTrie) touch(n *trieNode) {
+	name := n.name
+	v := t.root
+	for {
+		v.valid = false
+		if v.key == name {
+			v.node = n
+			return
+		}
+		pos := len(v.key)
+		i := sort.Search(len(v.children), func(i int) bool { return v.children[i].key[pos] >= name[pos] })
+		if i == len(v.children) || v.children[i].key[pos] != name[pos] {
+			v.insert(i, &hashNode{key: name, node: n})
+			return
+		}
+		child := v.children[i]
+		common := commonPrefix(child.key, name)
+		if common == len(child.key) {
+			v = child
+			continue
+		}
+		split := &hashNode{key: name[:common], children: []*hashNode{child}}
+		v.children[i] = split
+		if common == len(name) {
+			split.node = n
+			return
+		}
+		leaf := &hashNode{key: name, node: n}
+		if leaf.key[common] < child.key[common] {
+			split.insert(0, leaf)
+		} else {
+			split.insert(1, leaf)
+		}
+		return
+	}
+}
+
+func (v *hashNode) insert(i int, child *hashNode) {
+	v.children = append(v.children, nil)
+	copy(v.children[i+1:], v.children[i:])
+	v.children[i] = child
+}
+
+func (v *hashNode) invalidate() {
+	v.valid = false
+	for _, child := range v.children {
+		child.invalidate()
+	}
+}
+
+// rootHash returns the claimtrie root at the height. Before the AllClaimsInMerkle fork only the controlling claim of
+// a name is hashed, walking the name one character at a time, from the fork on every active claim is hashed into a
+// merkle tree per node of the compressed trie.
+func (t *hashTrie) rootHash(height int, allClaims bool) chainhash.Hash {
+	if allClaims != t.allClaims {
+		t.root.invalidate()
+		t.allClaims = allClaims
+	}
+	hash, present := t.nodeHash(t.root, height, true)
+	if !present {
+		return EmptyTrieHash
+	}
+	return hash
+}
+
+func (t *hashTrie) nodeHash(v *hashNode, height int, root bool) (chainhash.Hash, bool) {
+	if !v.valid {
+		if t.allClaims {
+			v.hash, v.present = t.allClaimsHash(v, height, root)
+		} else {
+			v.hash, v.present = t.controllingHash(v, height)
+		}
+		v.valid = true
+	}
+	return v.hash, v.present
+}
+
+func (t *hashTrie) controllingHash(v *hashNode, height int) (chainhash.Hash, bool) {
+	pos := len(v.key)
+	var buf []byte
+	for _, child := range v.children {
+		hash, present := t.nodeHash(child, height, false)
+		if !present {
+			continue
+		}
+		// the characters a compressed child skips are hashed as nodes with that child only
+		for i := len(child.key) - 1; i > pos; i-- {
+			hash = chainhash.DoubleHashH(append([]byte{child.key[i]}, hash[:]...))
+		}
+		buf = append(buf, child.key[pos])
+		buf = append(buf, hash[:]...)
+	}
+	if v.node != nil {
+		if best := v.node.best(height); best != nil {
+			hash := valueHash(best.Outpoint, v.node.controlling().Height)
+			buf = append(buf, hash[:]...)
+		}
+	}
+	if len(buf) == 0 {
+		return chainhash.Hash{}, false
+	}
+	return chainhash.DoubleHashH(buf), true
+}
+
+func (t *hashTrie) allClaimsHash(v *hashNode, height int, root bool) (chainhash.Hash, bool) {
+	var children []chainhash.Hash
+	for _, child := range v.children {
+		if hash, present := t.nodeHash(child, height, false); present {
+			children = append(children, hash)
+		}
+	}
+	var claims []chainhash.Hash
+	if v.node != nil {
+		takeoverHeight := v.node.controlling().Height
+		for _, claim := range v.node.active(height) {
+			claims = append(claims, valueHash(claim.Outpoint, takeoverHeight))
+		}
+	}
+	if len(claims) == 0 {
+		switch {
+		case len(children) == 0:
+			return chainhash.Hash{}, false
+		case len(children) == 1 && !root:
+			// a node left without claims and with a single child is merged into the child
+			return children[0], true
+		}
+	}
+	left, right := NoChildrenHash, NoClaimsHash
+	if len(children) > 0 {
+		left = merkleRoot(children)
+	}
+	if len(claims) > 0 {
+		right = merkleRoot(claims)
+	}
+	return chainhash.DoubleHashH(append(left[:], right[:]...)), true
+}
+
+// active returns the active claims of the node at the height, from the best to the worst.
+func (n *trieNode) active(height int) []*TrieClaim {
+	supported := make(map[string]uint64)
+	for _, support := range n.liveSupports {
+		if support.ActiveAt <= height {
+			supported[support.ClaimID] += support.Amount
+		}
+	}
+	var claims []*TrieClaim
+	for _, claim := range n.liveClaims {
+		if claim.ActiveAt <= height {
+			claims = append(claims, claim)
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		a, b := claims[i], claims[j]
+		return better(a.Amount+supported[a.ClaimID], a.AcceptedAt, a.Outpoint, b.Amount+supported[b.ClaimID], b.AcceptedAt, b.Outpoint)
+	})
+	return claims
+}
+
+// valueHash hashes the outpoint of a claim with the height of the last takeover of its name.
+func valueHash(outpoint Outpoint, takeoverHeight int) chainhash.Hash {
+	txHash, _ := hex.DecodeString(outpoint.TxID)
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, uint64(takeoverHeight))
+
+	buf := make([]byte, 0, 3*chainhash.HashSize)
+	for _, b := range [][]byte{util.ReverseBytes(txHash), []byte(strconv.Itoa(int(outpoint.Vout))), height} {
+		hash := chainhash.DoubleHashH(b)
+		buf = append(buf, hash[:]...)
+	}
+	return chainhash.DoubleHashH(buf)
+}
+
+func merkleRoot(hashes []chainhash.Hash) chainhash.Hash {
+	level := append([]chainhash.Hash{}, hashes...)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := 0; i < len(level); i += 2 {
+			level[i/2] = chainhash.DoubleHashH(append(level[i][:], level[i+1][:]...))
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0]
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,111 @@
+package claims
+
+import (
+	"encoding/hex"
+	"fast-blocks/blockchain/model"
+	"fast-blocks/global"
+	"fast-blocks/lbrycrd"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func dsha(parts ...[]byte) chainhash.Hash {
+	var buf []byte
+	for _, part := range parts {
+		buf = append(buf, part...)
+	}
+	return chainhash.DoubleHashH(buf)
+}
+
+func TestClaimTrieRootHash(t *testing.T) {
+	trie := NewClaimTrie()
+	if root := trie.hashes.rootHash(0, false); root != EmptyTrieHash {
+		t.Errorf("expected the empty trie hash, got %s", root)
+	}
+
+	txA, txB := strings.Repeat("0a", 32), strings.Repeat("0b", 32)
+	trie.Apply(model.Block{Height: 1, Transactions: []model.Transaction{
+		{Hash: txA, Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "tea", "c1", 0, 10)}},
+		{Hash: txB, Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "test", "c2", 1, 10)}},
+	}})
+	tea, test := valueHash(Outpoint{TxID: txA, Vout: 0}, 1), valueHash(Outpoint{TxID: txB, Vout: 1}, 1)
+
+	teaNode := dsha(tea[:])
+	testNode := dsha(test[:])
+	tesNode := dsha([]byte("t"), testNode[:])
+	teNode := dsha([]byte("a"), teaNode[:], []byte("s"), tesNode[:])
+	tNode := dsha([]byte("e"), teNode[:])
+	expected := dsha([]byte("t"), tNode[:])
+	if root := trie.hashes.rootHash(1, false); root != expected {
+		t.Errorf("expected root %s before the fork, got %s", expected, root)
+	}
+
+	teaNode = dsha(NoChildrenHash[:], tea[:])
+	testNode = dsha(NoChildrenHash[:], test[:])
+	children := dsha(teaNode[:], testNode[:])
+	teNode = dsha(children[:], NoClaimsHash[:])
+	expected = dsha(teNode[:], NoClaimsHash[:])
+	if root := trie.hashes.rootHash(1, true); root != expected {
+		t.Errorf("expected root %s after the fork, got %s", expected, root)
+	}
+}
+
+func TestClaimTrieRootDivergence(t *testing.T) {
+	trie := NewClaimTrie()
+	tx := model.Transaction{Hash: strings.Repeat("0a", 32), Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "one", "c1", 0, 10)}}
+	value := valueHash(Outpoint{TxID: tx.Hash}, 1)
+	root := dsha(value[:])
+	for _, c := range []string{"e", "n", "o"} {
+		root = dsha([]byte(c), root[:])
+	}
+
+	trie.Apply(model.Block{Height: 1, ClaimTrieRoot: hex.EncodeToString(root[:]), Transactions: []model.Transaction{tx}})
+	if err := trie.Divergence(); err != nil {
+		t.Fatalf("unexpected divergence %v", err)
+	}
+	trie.Apply(model.Block{Height: 2, BlockHash: "b2", ClaimTrieRoot: hex.EncodeToString(EmptyTrieHash[:])})
+	if err := trie.Divergence(); err == nil || err.Height != 2 || err.Computed != root.String() {
+		t.Errorf("expected a divergence at height 2, got %v", err)
+	}
+	trie.Apply(model.Block{Height: 3, ClaimTrieRoot: hex.EncodeToString(EmptyTrieHash[:])})
+	if err := trie.Divergence(); err.Height != 2 {
+		t.Errorf("expected the first divergence to be kept, got %v", err)
+	}
+}
+
+func TestClaimTrieRootTakeoverWorkaround(t *testing.T) {
+	wrongRoot := hex.EncodeToString(NoClaimsHash[:])
+	lbrycrd.SetTakeoverWorkarounds(nil)
+	defer resetTakeoverWorkarounds(t)
+	// roots are verified within and after the takeover workaround, even without its names
+	for _, height := range []int{496850, 658310} {
+		trie := NewClaimTrie()
+		trie.Apply(model.Block{Height: height, ClaimTrieRoot: wrongRoot})
+		if err := trie.Divergence(); err == nil || err.Height != height {
+			t.Errorf("expected a divergence at %d, got %v", height, err)
+		}
+	}
+}
+
+func TestClaimTrieRootHashFork(t *testing.T) {
+	global.BlockChainName = "lbrycrd_regtest"
+	defer func() { global.BlockChainName = "lbrycrd_main" }()
+	fork := lbrycrd.GetForks().AllClaimsInMerkle
+
+	trie := NewClaimTrie()
+	tx := model.Transaction{Hash: strings.Repeat("0a", 32), Outputs: []model.Output{claimOutput(lbrycrd.ClaimNameOp, "one", "c1", 0, 10)}}
+	trie.Apply(model.Block{Height: 1, Transactions: []model.Transaction{tx}})
+	for height := 2; height < fork-1; height++ {
+		trie.Apply(model.Block{Height: height})
+	}
+	if root := trie.RootHash(); root != trie.hashes.rootHash(fork-2, false) {
+		t.Errorf("expected the per character root two blocks before the fork, got %s", root)
+	}
+	// lbrycrd hashes a block with the rules of the next height
+	trie.Apply(model.Block{Height: fork - 1})
+	if root := trie.RootHash(); root != trie.hashes.rootHash(fork-1, true) {
+		t.Errorf("expected the all claims root one block before the fork, got %s", root)
+	}
+}
@@ -0,0 +1,5 @@
+package global
+
+// BlockChainName is the lbrycrd chain being loaded, one of lbrycrd_main, lbrycrd_testnet or lbrycrd_regtest. It
+// selects the address prefixes used when parsing scripts.
+var BlockChainName = "lbrycrd_main"
@@ -7,10 +7,12 @@ replace github.com/btcsuite/btcd => github.com/lbryio/lbrycrd.go v0.0.0-20200203
 require (
 	github.com/btcsuite/btcd v0.0.0-20190213025234-306aecffea32
 	github.com/btcsuite/btcutil v0.0.0-20190425235716-9e5f4b9a998d
-	github.com/genjidb/genji v0.14.0 // indirect
+	github.com/genjidb/genji v0.14.0
 	github.com/golang/protobuf v1.3.2
 	github.com/lbryio/lbry.go/v2 v2.7.1
 	github.com/lbryio/types v0.0.0-20201019032447-f0b4476ef386
 	github.com/sirupsen/logrus v1.8.1
-	golang.org/x/crypto v0.0.0-20191002192127-34f69633bfdc // indirect
+	github.com/syndtr/goleveldb v1.0.0
+	golang.org/x/crypto v0.0.0-20191002192127-34f69633bfdc
+	golang.org/x/text v0.3.6
 )
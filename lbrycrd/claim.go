@@ -0,0 +1,127 @@
+package lbrycrd
+
+import (
+	"fast-blocks/global"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/schema/stake"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+// Formats a claim value can be published in. The JSON metadata versions predate lbryschema, its v1 protobuf was
+// replaced by the current v2 protobuf.
+const (
+	ClaimFormatJSONV1     = "json-0.0.1"
+	ClaimFormatJSONV2     = "json-0.0.2"
+	ClaimFormatJSONV3     = "json-0.0.3"
+	ClaimFormatProtobufV1 = "protobuf-v1"
+	ClaimFormatProtobufV2 = "protobuf-v2"
+)
+
+const (
+	claimUnsigned = 0x00
+	claimSigned   = 0x01
+
+	// signedClaimHeaderSize is the version byte, the claim ID of the signing channel and the signature.
+	signedClaimHeaderSize = 1 + 20 + 64
+)
+
+// DecodeClaimValue decodes the value of a claim name or update claim script into the v2 claim model and reports the
+// format it was published in. Legacy JSON and v1 protobuf values are migrated to v2.
+func DecodeClaimValue(value []byte) (*pb.Claim, string, error) {
+	if len(value) == 0 {
+		return nil, "", errors.Err("claim value is empty")
+	}
+	switch value[0] {
+	case claimUnsigned, claimSigned:
+		claim, err := decodeClaimV2(value)
+		return claim, ClaimFormatProtobufV2, err
+	case '{':
+		return decodeClaimJSON(value)
+	default:
+		return decodeClaimV1(value)
+	}
+}
+
+// decodeClaimV2 decodes a value in the current format.
+func decodeClaimV2(value []byte) (*pb.Claim, error) {
+	payload, err := signedPayload(value)
+	if err != nil {
+		return nil, err
+	}
+	claim := &pb.Claim{}
+	err = proto.Unmarshal(payload, claim)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return claim, nil
+}
+
+// DecodeSupportValue decodes the value of a support with data. It is framed like a current claim value.
+func DecodeSupportValue(value []byte) (*pb.Support, error) {
+	payload, err := signedPayload(value)
+	if err != nil {
+		return nil, err
+	}
+	support := &pb.Support{}
+	err = proto.Unmarshal(payload, support)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return support, nil
+}
+
+// signedPayload returns the protobuf of a value starting with a version byte. Signed values follow it with the claim
+// ID of the signing channel and the signature.
+func signedPayload(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, errors.Err("value is empty")
+	}
+	switch value[0] {
+	case claimUnsigned:
+		return value[1:], nil
+	case claimSigned:
+		if len(value) < signedClaimHeaderSize {
+			return nil, errors.Err("signed value of %d bytes is too short", len(value))
+		}
+		return value[signedClaimHeaderSize:], nil
+	default:
+		return nil, errors.Err("unknown value version %d", value[0])
+	}
+}
+
+// decodeClaimV1 migrates a lbryschema v1 protobuf claim. Its first field is the schema version, so it never starts
+// with the version byte of a v2 value.
+func decodeClaimV1(value []byte) (*pb.Claim, string, error) {
+	helper, err := stake.DecodeClaimProtoBytes(value, global.BlockChainName)
+	if err != nil {
+		return nil, ClaimFormatProtobufV1, errors.Err(err)
+	}
+	if helper.LegacyClaim == nil {
+		return nil, "", errors.Err("claim value with version byte %d is not a v1 protobuf claim", value[0])
+	}
+	return helper.Claim, ClaimFormatProtobufV1, nil
+}
+
+// decodeClaimJSON migrates the JSON metadata of the first LBRY claims.
+func decodeClaimJSON(value []byte) (*pb.Claim, string, error) {
+	var format string
+	if (&stake.V1Claim{}).Unmarshal(value) == nil {
+		format = ClaimFormatJSONV1
+	} else if (&stake.V2Claim{}).Unmarshal(value) == nil {
+		format = ClaimFormatJSONV2
+	} else if (&stake.V3Claim{}).Unmarshal(value) == nil {
+		format = ClaimFormatJSONV3
+	} else {
+		return nil, "", errors.Err("claim value is not JSON metadata of a known version")
+	}
+	helper, err := stake.DecodeClaimBytes(value, global.BlockChainName)
+	if err != nil {
+		return nil, format, errors.Err(err)
+	}
+	if helper.Payload != nil {
+		return nil, format, errors.Err("JSON claim value decoded as protobuf")
+	}
+	return helper.Claim, format, nil
+}
@@ -0,0 +1,62 @@
+package lbrycrd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func TestDecodeClaimValue(t *testing.T) {
+	claim := &pb.Claim{Title: "fast blocks", Type: &pb.Claim_Stream{Stream: &pb.Stream{}}}
+	payload, err := proto.Marshal(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := append([]byte{claimSigned}, make([]byte, 84)...)
+	for _, value := range [][]byte{append([]byte{claimUnsigned}, payload...), append(signed, payload...)} {
+		decoded, format, err := DecodeClaimValue(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.GetTitle() != "fast blocks" || decoded.GetStream() == nil || format != ClaimFormatProtobufV2 {
+			t.Errorf("unexpected %s claim %s", format, decoded)
+		}
+	}
+
+	for _, value := range [][]byte{nil, {claimSigned, 1, 2}, {7, 1}, []byte(`{"ver": "0.0.9"}`)} {
+		if _, _, err = DecodeClaimValue(value); err == nil {
+			t.Errorf("expected %x to fail", value)
+		}
+	}
+}
+
+func TestDecodeLegacyClaimValue(t *testing.T) {
+	script, _ := hex.DecodeString("b506676f6f676c654dd0017b22766572223a2022302e302e33222c20226465736372697074696f6e223a20226d6520616e6420406b6c69707a6f6f2061726520747279696e6720746f20666967757265206f757420686f77207468697320776f726b73222c20226c6963656e7365223a2022437265617469766520436f6d6d6f6e73204174747269627574696f6e20342e3020496e7465726e6174696f6e616c222c2022617574686f72223a20224e696b6f6f6f373737222c20227469746c65223a20225468697320697320612074657374222c20226c616e6775616765223a2022656e222c20226e736677223a2066616c73652c2022636f6e74656e745f74797065223a2022696d6167652f706e67222c20226c6963656e73655f75726c223a202268747470733a2f2f6372656174697665636f6d6d6f6e732e6f72672f6c6963656e7365732f62792f342e302f6c6567616c636f6465222c2022736f7572636573223a207b226c6272795f73645f68617368223a2022313564623662343761666363646536363933396131353639303765656638616134316239666233353664643439396138343964663566656464313837636264333734326132623232653539663438356263346561626364636666383739663762227d7d6d7576a914c42a72a4a553138b1ae1270de25283b37966e54888ac")
+	_, jsonV3, _, err := ParseClaimNameScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonV1 := []byte(`{"title": "first", "description": "", "author": "", "language": "en", "license": "", "content-type": "video/mp4", "sources": {"lbry_sd_hash": "15db6b47afccde66939a156907eef8aa41b9fb356dd499a849df5fedd187cbd3742a2b22e59f485bc4eabcdcff879f7b"}}`)
+	pbV1, _ := hex.DecodeString("08011002225e0801100322583056301006072a8648ce3d020106052b8104000a03420004d015365a40f3e5c03c87227168e5851f44659837bcf6a3398ae633bc37d04ee19baeb26dc888003bd728146dbea39f5344bf8c52cedaf1a3a1623a0166f4a367")
+
+	tests := []struct {
+		value  []byte
+		format string
+		check  func(claim *pb.Claim) bool
+	}{
+		{jsonV3, ClaimFormatJSONV3, func(claim *pb.Claim) bool { return claim.GetTitle() == "This is a test" }},
+		{jsonV1, ClaimFormatJSONV1, func(claim *pb.Claim) bool { return claim.GetTitle() == "first" }},
+		{pbV1, ClaimFormatProtobufV1, func(claim *pb.Claim) bool { return claim.GetChannel() != nil }},
+	}
+	for _, test := range tests {
+		claim, format, err := DecodeClaimValue(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != test.format || !test.check(claim) {
+			t.Errorf("unexpected %s claim %s", format, claim)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package lbrycrd
+
+import "fast-blocks/global"
+
+// Forks are the heights lbrycrd changed its claim rules at. See https://github.com/lbryio/lbrycrd/blob/master/src/chainparams.cpp
+type Forks struct {
+	// OriginalExpiration and ExtendedExpiration are how many blocks claims and supports live before and after the
+	// ExtendedClaimExpiration fork.
+	OriginalExpiration int
+	ExtendedExpiration int
+	// ExtendedClaimExpiration extends the expiration of claims that have not expired yet.
+	ExtendedClaimExpiration int
+	// NormalizedName compares claim names after unicode normalization and case folding.
+	NormalizedName int
+	// AllClaimsInMerkle hashes every claim of a name into the claimtrie root and allows supports to carry data.
+	AllClaimsInMerkle int
+	// MinTakeoverWorkaround and MaxTakeoverWorkaround bound the heights of the takeover workaround, see
+	// TakeoverWorkaround. Only mainnet has one.
+	MinTakeoverWorkaround int
+	MaxTakeoverWorkaround int
+}
+
+var forksMap = map[string]Forks{
+	lbrycrdMain: {OriginalExpiration: 262974, ExtendedExpiration: 2102400,
+		ExtendedClaimExpiration: 400155, NormalizedName: 539940, AllClaimsInMerkle: 658310,
+		MinTakeoverWorkaround: 496850, MaxTakeoverWorkaround: 658300},
+	lbrycrdTestnet: {OriginalExpiration: 262974, ExtendedExpiration: 2102400,
+		ExtendedClaimExpiration: 278160, NormalizedName: 993380, AllClaimsInMerkle: 1198560},
+	lbrycrdRegtest: {OriginalExpiration: 500, ExtendedExpiration: 600,
+		ExtendedClaimExpiration: 800, NormalizedName: 250, AllClaimsInMerkle: 350},
+}
+
+// GetForks returns the fork heights of the currently set blockchain name.
+func GetForks() Forks {
+	forks, ok := forksMap[global.BlockChainName]
+	if !ok {
+		return forksMap[lbrycrdMain]
+	}
+	return forks
+}
+
+// SupportsWithData returns true if supports at the height may carry a value.
+func (f Forks) SupportsWithData(height int) bool {
+	return height >= f.AllClaimsInMerkle
+}
+
+// ExpirationHeight returns the first height a claim or support created at the height is expired at. Those still alive
+// when the ExtendedClaimExpiration fork activates get the extended expiration.
+func (f Forks) ExpirationHeight(height int) int {
+	if height+f.OriginalExpiration >= f.ExtendedClaimExpiration {
+		return height + f.ExtendedExpiration
+	}
+	return height + f.OriginalExpiration
+}
@@ -0,0 +1,79 @@
+package lbrycrd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpirationHeight(t *testing.T) {
+	forks := forksMap[lbrycrdMain]
+	tests := map[int]int{
+		1:      1 + 262974, // expired long before the fork
+		137180: 137180 + 262974,
+		137181: 137181 + 2102400, // reaches the fork
+		400155: 400155 + 2102400,
+	}
+	for height, expiration := range tests {
+		if e := forks.ExpirationHeight(height); e != expiration {
+			t.Errorf("expected claim at %d to expire at %d, got %d", height, expiration, e)
+		}
+	}
+}
+
+func TestSupportsWithData(t *testing.T) {
+	forks := forksMap[lbrycrdMain]
+	if forks.SupportsWithData(658309) || !forks.SupportsWithData(658310) {
+		t.Error("expected supports to carry data from height 658310 on")
+	}
+}
+
+func TestTakeoverWorkaround(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "takeovers.txt")
+	err := os.WriteFile(path, []byte("# comment\n496856_HunterxHunterAMV\n\n542978_name_with_underscores\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = LoadTakeoverWorkarounds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resetTakeoverWorkarounds(t)
+	forks := forksMap[lbrycrdMain]
+	if !forks.TakeoverWorkaround(496856, "HunterxHunterAMV") || !forks.TakeoverWorkaround(542978, "name_with_underscores") {
+		t.Error("expected the listed names to be taken over")
+	}
+	if forks.TakeoverWorkaround(496857, "HunterxHunterAMV") || forks.TakeoverWorkaround(496856, "other") {
+		t.Error("expected only the listed heights and names to be taken over")
+	}
+	if forksMap[lbrycrdTestnet].TakeoverWorkaround(496856, "HunterxHunterAMV") {
+		t.Error("expected no takeover workaround on testnet")
+	}
+
+	err = os.WriteFile(path, []byte("HunterxHunterAMV\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = LoadTakeoverWorkarounds(path); err == nil {
+		t.Error("expected a line without a height to fail")
+	}
+}
+
+// resetTakeoverWorkarounds restores the embedded takeover workarounds.
+func resetTakeoverWorkarounds(t *testing.T) {
+	keys, err := DefaultTakeoverWorkarounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTakeoverWorkarounds(keys)
+}
+
+func TestDefaultTakeoverWorkarounds(t *testing.T) {
+	keys, err := DefaultTakeoverWorkarounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TakeoverWorkaroundsLoaded() != (keys != nil) {
+		t.Errorf("expected the embedded takeover workarounds to be loaded if there are any, got %d", len(keys))
+	}
+}
@@ -0,0 +1,25 @@
+package lbrycrd
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName returns the NFD normalized, lowercased form lbrycrd compares claim names in from the NormalizedName
+// fork on. Names that are not valid UTF-8 are left as they are.
+func NormalizeName(name string) string {
+	if !utf8.ValidString(name) {
+		return name
+	}
+	return strings.ToLower(norm.NFD.String(name))
+}
+
+// TrieName returns the name a claim made at the height competes for in the claimtrie.
+func (f Forks) TrieName(name string, height int) string {
+	if height < f.NormalizedName {
+		return name
+	}
+	return NormalizeName(name)
+}
@@ -0,0 +1,22 @@
+package lbrycrd
+
+import "testing"
+
+func TestTrieName(t *testing.T) {
+	forks := forksMap[lbrycrdMain]
+	tests := []struct {
+		name     string
+		height   int
+		expected string
+	}{
+		{"Hello", forks.NormalizedName - 1, "Hello"},
+		{"Hello", forks.NormalizedName, "hello"},
+		{"Caf\u00e9", forks.NormalizedName, "cafe\u0301"},    // decomposed
+		{"\xff\xfeABC", forks.NormalizedName, "\xff\xfeABC"}, // invalid UTF-8 stays as it is
+	}
+	for _, test := range tests {
+		if name := forks.TrieName(test.name, test.height); name != test.expected {
+			t.Errorf("expected %q at %d to be %q, got %q", test.name, test.height, test.expected, name)
+		}
+	}
+}
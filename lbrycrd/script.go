@@ -3,6 +3,7 @@ package lbrycrd
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"fast-blocks/global"
 	"fast-blocks/util"
 	"github.com/golang/protobuf/proto"
 
@@ -21,6 +22,7 @@ const (
 	opSupportClaim = 0xb6 //OP_NOP7 			= 182
 	opUpdateClaim  = 0xb7 //OP_NOP8 			= 183
 	opReturn       = 0x6a //OP_RETURN       	= 106
+	op2Drop        = 0x6d //OP_2DROP        	= 109
 	purchase       = 0x50 //PURCHASE = 80
 	opDup          = 0x76 //opDup 				= 118
 	opChecksig     = 0xac //opChecksig 			= 172
@@ -41,6 +43,11 @@ const (
 	// NullData Transaction type related to segwit outputs
 	NullData = "nulldata"
 
+	// Claim operations of a claim script.
+	ClaimNameOp    = "claim_name"
+	UpdateClaimOp  = "update_claim"
+	SupportClaimOp = "support_claim"
+
 	lbrycrdMainPubkeyPrefix    = byte(85)
 	lbrycrdMainScriptPrefix    = byte(122)
 	lbrycrdTestnetPubkeyPrefix = byte(111)
@@ -86,9 +93,9 @@ func init() {
 
 //GetChainParams returns the currently set blockchain name as the chain parameters. Set in the config.
 func GetChainParams() (*chaincfg.Params, error) {
-	chainParams, ok := paramsMap[lbrycrdMain]
+	chainParams, ok := paramsMap[global.BlockChainName]
 	if !ok {
-		return nil, errors.Err("unknown chain name %s", lbrycrdMain)
+		return nil, errors.Err("unknown chain name %s", global.BlockChainName)
 	}
 
 	return &chainParams, nil
@@ -172,10 +179,17 @@ func ParseClaimNameScript(script []byte) (name string, value []byte, pubkeyscrip
 	return name, value, pubkeyscript, err
 }
 
-// ParseClaimSupportScript parses a script for a support of a claim.
+// ParseClaimSupportScript parses a script for a support of a claim. The value of a support with data is skipped.
 func ParseClaimSupportScript(script []byte) (name string, claimid string, pubkeyscript []byte, err error) {
+	name, claimid, _, pubkeyscript, err = ParseClaimSupportDataScript(script)
+	return
+}
+
+// ParseClaimSupportDataScript parses a script for a support of a claim that may carry a value after the claim ID.
+func ParseClaimSupportDataScript(script []byte) (name string, claimid string, value []byte, pubkeyscript []byte, err error) {
 	// Already validated by blockchain so can be assumed
 	// opSupportClaim vchName vchClaimId OP_2DROP OP_DROP pubkeyscript
+	// opSupportClaim vchName vchClaimId vchValue OP_2DROP OP_2DROP pubkeyscript
 
 	//Name
 	nameBytesToRead := int(script[1])
@@ -197,12 +211,50 @@ func ParseClaimSupportScript(script []byte) (name string, claimid string, pubkey
 	bytes := util.ReverseBytes(script[claimidStart:claimidEnd])
 	claimid = hex.EncodeToString(bytes)
 
+	//Value
+	pksStart := claimidEnd + 2 // +2 to ignore OP_2DROP and OP_DROP
+	if script[claimidEnd] != op2Drop {
+		var valueEnd int
+		value, valueEnd, err = parsePushData(script, claimidEnd)
+		if err != nil {
+			return
+		}
+		pksStart = valueEnd + 2 // +2 to ignore OP_2DROP and OP_2DROP
+	}
+
 	//PubKeyScript
-	pksStart := claimidEnd + 2       // +2 to ignore OP_2DROP and OP_DROP
+	if pksStart > len(script) {
+		err = errors.Err("support script of %d bytes is truncated", len(script))
+		return
+	}
 	pubkeyscript = script[pksStart:] //Remainder is always pubkeyscript
 	return
 }
 
+// parsePushData returns the data pushed by the opcode at start and where it ends.
+func parsePushData(script []byte, start int) (data []byte, end int, err error) {
+	dataPushType := int(script[start])
+	dataBytesToRead := dataPushType
+	dataStart := start + 1
+	if dataPushType == opPushdata1 && len(script) > start+1 {
+		dataBytesToRead = int(script[start+1])
+		dataStart = start + 2
+	} else if dataPushType == opPushdata2 && len(script) > start+2 {
+		dataBytesToRead = int(binary.LittleEndian.Uint16(script[start+1 : start+3]))
+		dataStart = start + 3
+	} else if dataPushType == opPushdata4 && len(script) > start+4 {
+		dataBytesToRead = int(binary.LittleEndian.Uint32(script[start+1 : start+5]))
+		dataStart = start + 5
+	} else if dataPushType > opPushdata1 {
+		return nil, 0, errors.Err("opcode %x at %d is not a data push", dataPushType, start)
+	}
+	end = dataStart + dataBytesToRead
+	if end > len(script) {
+		return nil, 0, errors.Err("data push of %d bytes at %d exceeds the script", dataBytesToRead, start)
+	}
+	return script[dataStart:end], end, nil
+}
+
 // ParseClaimUpdateScript parses a script for an update of a claim.
 func ParseClaimUpdateScript(script []byte) (name string, claimid string, value []byte, pubkeyscript []byte, err error) {
 	// opUpdateClaim Name ClaimID Value OP_2DROP OP_2DROP pubkeyscript
@@ -2,6 +2,7 @@ package lbrycrd
 
 import (
 	"encoding/hex"
+	"fast-blocks/global"
 	"fast-blocks/util"
 	"testing"
 
@@ -0,0 +1,124 @@
+package lbrycrd
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// takeoverWorkarounds holds the names lbrycrd takes over at a height although their controlling claim does not
+// change, keyed like lbcd's claimtrie/param/takeovers.go: the height and the name joined by an underscore.
+var takeoverWorkarounds struct {
+	sync.Mutex
+	keys   map[string]bool
+	loaded bool
+}
+
+//go:generate go run takeovers_gen.go -source https://raw.githubusercontent.com/lbryio/lbcd/master/claimtrie/param/takeovers.go
+
+// embeddedTakeovers is lbcd's list of takeover workarounds, generated into takeovers.txt.
+//
+//go:embed takeovers.txt
+var embeddedTakeovers string
+
+func init() {
+	keys, err := DefaultTakeoverWorkarounds()
+	if err != nil {
+		panic("failed to parse the embedded takeover workarounds: " + err.Error())
+	}
+	if keys != nil {
+		SetTakeoverWorkarounds(keys)
+	}
+}
+
+// DefaultTakeoverWorkarounds returns the keys of lbrycrd's takeover workaround embedded in the module, nil if the
+// embedded list is empty.
+func DefaultTakeoverWorkarounds() ([]string, error) {
+	keys, err := parseTakeoverWorkarounds(strings.NewReader(embeddedTakeovers), "takeovers.txt")
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// LoadTakeoverWorkarounds replaces the embedded takeover workarounds with the ones of a file holding one key of lbcd's
+// TakeoverWorkarounds per line, like 496856_HunterxHunterAMV. Without them the claimtrie differs from lbrycrd
+// between the MinTakeoverWorkaround and MaxTakeoverWorkaround heights.
+func LoadTakeoverWorkarounds(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer file.Close()
+	keys, err := parseTakeoverWorkarounds(file, path)
+	if err != nil {
+		return err
+	}
+	SetTakeoverWorkarounds(keys)
+	return nil
+}
+
+// parseTakeoverWorkarounds reads one key per line, skipping empty lines and comments starting with #.
+func parseTakeoverWorkarounds(r io.Reader, source string) ([]string, error) {
+	keys := []string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || strings.HasPrefix(key, "#") {
+			continue
+		}
+		i := strings.Index(key, "_")
+		if i < 0 {
+			return nil, errors.Err("line %d of %s is not a height and a name joined by _", line, source)
+		}
+		if _, err := strconv.Atoi(key[:i]); err != nil {
+			return nil, errors.Err("line %d of %s does not start with a height", line, source)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Err(err)
+	}
+	return keys, nil
+}
+
+// SetTakeoverWorkarounds sets the keys of lbrycrd's takeover workaround, nil unloads them.
+func SetTakeoverWorkarounds(keys []string) {
+	takeoverWorkarounds.Lock()
+	defer takeoverWorkarounds.Unlock()
+	takeoverWorkarounds.keys = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		takeoverWorkarounds.keys[key] = true
+	}
+	takeoverWorkarounds.loaded = keys != nil
+}
+
+// TakeoverWorkaroundsLoaded returns true once takeover workarounds are set.
+func TakeoverWorkaroundsLoaded() bool {
+	takeoverWorkarounds.Lock()
+	defer takeoverWorkarounds.Unlock()
+	return takeoverWorkarounds.loaded
+}
+
+// InTakeoverWorkaround returns true if the takeover workaround applies to blocks at the height.
+func (f Forks) InTakeoverWorkaround(height int) bool {
+	return height >= f.MinTakeoverWorkaround && height < f.MaxTakeoverWorkaround
+}
+
+// TakeoverWorkaround returns true if lbrycrd takes the name over at the height even though its best claim already
+// controls it. Earlier versions of lbrycrd did so for names changed in some ways, later versions list them to stay
+// in consensus.
+func (f Forks) TakeoverWorkaround(height int, name string) bool {
+	if !f.InTakeoverWorkaround(height) {
+		return false
+	}
+	takeoverWorkarounds.Lock()
+	defer takeoverWorkarounds.Unlock()
+	return takeoverWorkarounds.keys[strconv.Itoa(height)+"_"+name]
+}
@@ -0,0 +1,106 @@
+//go:build ignore
+// +build ignore
+
+// takeovers_gen writes takeovers.txt from the TakeoverWorkarounds map of lbcd's claimtrie/param/takeovers.go, read
+// from the path or URL given as -source.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const header = `# lbrycrd's takeover workarounds, one key of lbcd's TakeoverWorkarounds per line: a height and a name joined by _.
+# Generated from %s by takeovers_gen.go, run go generate ./lbrycrd to update it.
+`
+
+func main() {
+	location := flag.String("source", "", "path or URL of lbcd's claimtrie/param/takeovers.go")
+	flag.Parse()
+	if *location == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	source, err := read(*location)
+	if err != nil {
+		fail(err)
+	}
+	keys, err := parse(source)
+	if err != nil {
+		fail(err)
+	}
+	if len(keys) == 0 {
+		fail(fmt.Errorf("no TakeoverWorkarounds found in %s", *location))
+	}
+	out := bytes.NewBufferString(fmt.Sprintf(header, *location))
+	for _, key := range keys {
+		out.WriteString(key + "\n")
+	}
+	err = ioutil.WriteFile("takeovers.txt", out.Bytes(), 0644)
+	if err != nil {
+		fail(err)
+	}
+}
+
+func read(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(location)
+	}
+	resp, err := http.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", location, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parse returns the keys of the TakeoverWorkarounds map literal in their order in the source.
+func parse(source []byte) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "takeovers.go", source, 0)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "TakeoverWorkarounds" || len(spec.Values) != 1 {
+			return true
+		}
+		literal, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, element := range literal.Elts {
+			kv, ok := element.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.BasicLit)
+			if !ok || key.Kind != token.STRING {
+				continue
+			}
+			if value, err := strconv.Unquote(key.Value); err == nil {
+				keys = append(keys, value)
+			}
+		}
+		return false
+	})
+	return keys, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -26,37 +26,53 @@ func LoadChain(chain blockchain.Chain) error {
 		}
 	}
 	close(results)
+	err := chain.Flush()
+	if err != nil {
+		return err
+	}
+	if corruptions := chain.Corruptions(); len(corruptions) > 0 {
+		var skipped int64
+		for _, c := range corruptions {
+			skipped += c.Skipped
+		}
+		logrus.Warn("Skipped ", len(corruptions), " corrupt regions, ", skipped, " bytes in total")
+	}
 	return nil
 }
 
 func startLoadWorker(worker int, chain blockchain.Chain, results chan<- error) {
 	var err error
-	var height int
+	var blocks int
 	var fileNr int
 Files:
 	for {
 		var blockStream stream.Blocks
-		blockStream, err = chain.NextBlockFile(height)
+		blockStream, err = chain.NextBlockFile()
 		if err != nil {
 			break
 		}
 		for {
 			if blockStream == nil {
 				println("finished processing files :) ")
-				return // Need to go into a minitoring mode
+				break Files // Need to go into a minitoring mode
 			}
 			var block *model.Block
 			block, err = blockStream.NextBlock()
 			if err != nil {
 				if errors.Is(err, io.EOF) {
+					blockStream.Close()
 					break
 				}
+				if merkleErr, ok := err.(*stream.MerkleError); ok {
+					logrus.Error(merkleErr)
+					continue
+				}
 				break Files
 			}
 			chain.Notify(*block)
-			height = block.Height
-			if height%1000 == 0 {
-				logrus.Info("Worker: ", worker, " Blockfile: ", blockStream.BlockFile(), ", Block Nr: ", height, " Txs: ", len(block.Transactions))
+			blocks++
+			if blocks%1000 == 0 {
+				logrus.Info("Worker: ", worker, " Blockfile: ", blockStream.BlockFile(), ", Blocks: ", blocks, " Txs: ", len(block.Transactions))
 			}
 
 		}
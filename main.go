@@ -3,22 +3,41 @@ package main
 import (
 	"fast-blocks/blockchain"
 	"fast-blocks/blockchain/model"
+	"fast-blocks/blockchain/utxo"
+	"fast-blocks/claims"
+	"fast-blocks/lbrycrd"
 	"fast-blocks/loader"
 	"fast-blocks/server"
 	"fast-blocks/storage"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/sirupsen/logrus"
+	"os"
 )
 
 func main() {
-	server.Start()
+	if _, err := os.Stat("./takeovers.txt"); err == nil {
+		err = lbrycrd.LoadTakeoverWorkarounds("./takeovers.txt")
+		if err != nil {
+			logrus.Fatal(errors.FullTrace(err))
+		}
+	}
+	trie := claims.NewClaimTrie()
+	index := claims.NewIndex(trie.Tracker())
+	aggregator := claims.NewSupportAggregator(trie)
+	aggregator.OnChange(storage.SaveClaimAmounts)
+	searchIndex := claims.NewSearchIndex(trie.Tracker(), aggregator)
+	server.Start(claims.NewResolver(trie, index, aggregator), index, aggregator, searchIndex)
 	storage.Start()
-	//chain, err := blockchain.New(blockchain.Config{BlocksDir: "/home/odysee/fast-blocks/blocks/"}) //, BlockFile: "blocks/blk00038.dat"})
-	//chain, err := blockchain.New(blockchain.Config{BlocksDir: "./blocks/"})
-	chain, err := blockchain.New(blockchain.Config{BlocksDir: "./blocks/", BlockFile: "blocks/blk00038.dat"})
+	//chain, err := blockchain.New(blockchain.Config{BlocksDir: "/home/odysee/fast-blocks/blocks/"})
+	chain, err := blockchain.New(blockchain.Config{BlocksDir: "./blocks/", VerifySignatures: true})
 	if err != nil {
 		logrus.Fatal(errors.FullTrace(err))
 	}
+	utxos := utxo.New()
+	chain.OnBlock(func(block model.Block) {
+		trie.Apply(block)
+		utxos.Apply(block)
+	})
 	chain.OnOutput(func(vout model.Output) {
 		println("Type: ", vout.ScriptType, " Address: ", vout.Address.Encoded, " Amount: ", vout.Amount)
 	})
@@ -26,4 +45,5 @@ func main() {
 	if err != nil {
 		logrus.Error(errors.FullTrace(err))
 	}
+	logrus.Info("Unspent outputs: ", utxos.Len(), " at height ", utxos.Height(), ", spends of unknown outputs: ", utxos.Missing())
 }
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"fast-blocks/claims"
+	"math"
+	"net/http"
+)
+
+type amountsResponse struct {
+	ClaimID         string `json:"claim_id"`
+	Name            string `json:"name"`
+	Height          int    `json:"height"`
+	Bid             uint64 `json:"bid"`
+	OwnerSupports   uint64 `json:"owner_supports"`
+	Tips            uint64 `json:"tips"`
+	EffectiveAmount uint64 `json:"effective_amount"`
+}
+
+// amounts returns the amounts of claim_id as of height, the last block by default.
+func amounts(aggregator *claims.SupportAggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		height, err := intValue(r, "height", math.MaxInt32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid height"))
+			return
+		}
+		a, ok := aggregator.AmountsAt(r.FormValue("claim_id"), height)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("claim not found"))
+			return
+		}
+		b, err := json.Marshal(amountsResponse{
+			ClaimID:         a.ClaimID,
+			Name:            a.Name,
+			Height:          a.Height,
+			Bid:             a.Bid,
+			OwnerSupports:   a.OwnerSupports,
+			Tips:            a.Tips,
+			EffectiveAmount: a.EffectiveAmount(),
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Write(b)
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"fast-blocks/claims"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+type claimsPage struct {
+	Claims   []claimResponse `json:"claims"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	Total    int             `json:"total"`
+}
+
+// related lists a page of the live claims related to claim_id by list.
+func related(resolver *claims.Resolver, index *claims.Index, list func(claimID string) []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeClaimsPage(w, r, resolver, index, list(r.FormValue("claim_id")))
+	})
+}
+
+// writeClaimsPage writes the page of the live claims given by the page and page_size parameters, starting at page 1.
+func writeClaimsPage(w http.ResponseWriter, r *http.Request, resolver *claims.Resolver, index *claims.Index, claimIDs []string) {
+	page, err := intValue(r, "page", 1)
+	if err != nil || page < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid page"))
+		return
+	}
+	pageSize, err := intValue(r, "page_size", defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid page_size"))
+		return
+	}
+
+	var live []*claims.Claim
+	for _, claimID := range claimIDs {
+		if claim, ok := resolver.Claim(claimID); ok {
+			live = append(live, claim)
+		}
+	}
+	result := claimsPage{Claims: make([]claimResponse, 0, pageSize), Page: page, PageSize: pageSize, Total: len(live)}
+	for i := (page - 1) * pageSize; i < len(live) && i < page*pageSize; i++ {
+		result.Claims = append(result.Claims, newClaimResponse(live[i], resolver, index))
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Write(b)
+}
+
+func intValue(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.FormValue(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
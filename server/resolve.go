@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"fast-blocks/claims"
+	"net/http"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+type claimResponse struct {
+	ClaimID          string    `json:"claim_id"`
+	Name             string    `json:"name"`
+	NormalizedName   string    `json:"normalized_name"`
+	ShortURL         string    `json:"short_url"`
+	CanonicalURL     string    `json:"canonical_url"`
+	TxID             string    `json:"txid"`
+	Nout             uint32    `json:"nout"`
+	Amount           uint64    `json:"amount"`
+	EffectiveAmount  uint64    `json:"effective_amount"`
+	Height           int       `json:"height"`
+	UpdateHeight     int       `json:"update_height"`
+	ExpirationHeight int       `json:"expiration_height"`
+	SigningChannelID string    `json:"signing_channel_id,omitempty"`
+	IsSignatureValid bool      `json:"is_signature_valid"`
+	ValueType        string    `json:"value_type,omitempty"`
+	Value            *pb.Claim `json:"value,omitempty"`
+}
+
+func newClaimResponse(claim *claims.Claim, resolver *claims.Resolver, index *claims.Index) claimResponse {
+	shortURL, _ := index.ShortURL(claim.ClaimID)
+	canonicalURL, _ := index.CanonicalURL(claim.ClaimID)
+	return claimResponse{
+		ClaimID:          claim.ClaimID,
+		Name:             claim.Name,
+		NormalizedName:   claim.NormalizedName,
+		ShortURL:         shortURL,
+		CanonicalURL:     canonicalURL,
+		TxID:             claim.Outpoint.TxID,
+		Nout:             claim.Outpoint.Vout,
+		Amount:           claim.Amount,
+		EffectiveAmount:  resolver.EffectiveAmount(claim.ClaimID),
+		Height:           claim.Height,
+		UpdateHeight:     claim.UpdateHeight,
+		ExpirationHeight: claim.ExpirationHeight,
+		SigningChannelID: claim.Output.SigningChannelID,
+		IsSignatureValid: claim.Output.IsSignatureValid,
+		ValueType:        claim.Output.ClaimFormat,
+		Value:            claim.Output.Claim,
+	}
+}
+
+func resolve(resolver *claims.Resolver, index *claims.Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claim, err := resolver.Resolve(r.FormValue("url"))
+		if err != nil {
+			if errors.Is(err, claims.ErrClaimNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			w.Write([]byte(err.Error()))
+			return
+		}
+		b, err := json.Marshal(newClaimResponse(claim, resolver, index))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Write(b)
+	})
+}
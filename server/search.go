@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fast-blocks/claims"
+	"net/http"
+	"strings"
+)
+
+// search lists a page of the claims matching the text, all_tags, any_tags, languages, locations and claim_types
+// parameters, ordered by order_by. List parameters may be repeated or comma separated.
+func search(searchIndex *claims.SearchIndex, resolver *claims.Resolver, index *claims.Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := claims.SearchQuery{
+			Text:       r.FormValue("text"),
+			AllTags:    listValue(r, "all_tags"),
+			AnyTags:    listValue(r, "any_tags"),
+			Languages:  listValue(r, "languages"),
+			Locations:  listValue(r, "locations"),
+			ClaimTypes: listValue(r, "claim_types"),
+			OrderBy:    r.FormValue("order_by"),
+		}
+		switch strings.TrimPrefix(query.OrderBy, "^") {
+		case "", claims.OrderByEffectiveAmount, claims.OrderByHeight:
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid order_by"))
+			return
+		}
+		writeClaimsPage(w, r, resolver, index, searchIndex.Search(query))
+	})
+}
+
+func listValue(r *http.Request, key string) []string {
+	if err := r.ParseForm(); err != nil {
+		return nil
+	}
+	var values []string
+	for _, value := range r.Form[key] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fast-blocks/claims"
 	"fast-blocks/storage"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/types"
@@ -10,9 +11,16 @@ import (
 	"net/http"
 )
 
-func Start() {
+func Start(resolver *claims.Resolver, index *claims.Index, aggregator *claims.SupportAggregator, searchIndex *claims.SearchIndex) {
 	httpServeMux := http.NewServeMux()
 	httpServeMux.Handle("/sql", query())
+	httpServeMux.Handle("/resolve", resolve(resolver, index))
+	httpServeMux.Handle("/channel/claims", related(resolver, index, index.ChannelClaims))
+	httpServeMux.Handle("/reposts", related(resolver, index, index.Reposts))
+	httpServeMux.Handle("/collection/claims", related(resolver, index, index.CollectionClaims))
+	httpServeMux.Handle("/claim/amounts", amounts(aggregator))
+	httpServeMux.Handle("/search", search(searchIndex, resolver, index))
+	httpServeMux.Handle("/claims/by_hash", byHash(resolver, index))
 	go func() {
 		err := http.ListenAndServe(":8855", httpServeMux)
 		if err != nil {
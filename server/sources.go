@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"fast-blocks/claims"
+	"net/http"
+)
+
+type claimVersionResponse struct {
+	ClaimID   string `json:"claim_id"`
+	TxID      string `json:"txid"`
+	Nout      uint32 `json:"nout"`
+	Height    int    `json:"height"`
+	IsCurrent bool   `json:"is_current"`
+}
+
+// byHash lists every version of the claims referencing sd_hash, or source_hash when no sd_hash is given.
+func byHash(resolver *claims.Resolver, index *claims.Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var versions []claims.ClaimVersion
+		if sdHash := r.FormValue("sd_hash"); sdHash != "" {
+			versions = index.BySdHash(sdHash)
+		} else if hash := r.FormValue("source_hash"); hash != "" {
+			versions = index.BySourceHash(hash)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("sd_hash or source_hash is required"))
+			return
+		}
+		result := make([]claimVersionResponse, 0, len(versions))
+		for _, version := range versions {
+			claim, ok := resolver.Claim(version.ClaimID)
+			result = append(result, claimVersionResponse{
+				ClaimID:   version.ClaimID,
+				TxID:      version.Outpoint.TxID,
+				Nout:      version.Outpoint.Vout,
+				Height:    version.Height,
+				IsCurrent: ok && claim.Outpoint == version.Outpoint,
+			})
+		}
+		b, err := json.Marshal(result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Write(b)
+	})
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"fast-blocks/claims"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// claimAmounts is a row of the claim_amounts table, one for every block changing the amounts of a claim.
+type claimAmounts struct {
+	ClaimID         string `genji:"claim_id"`
+	Name            string `genji:"name"`
+	Height          int    `genji:"height"`
+	Bid             uint64 `genji:"bid"`
+	OwnerSupports   uint64 `genji:"owner_supports"`
+	Tips            uint64 `genji:"tips"`
+	EffectiveAmount uint64 `genji:"effective_amount"`
+}
+
+// SaveClaimAmounts stores the amounts of a claim, typically from SupportAggregator.OnChange.
+func SaveClaimAmounts(amounts claims.Amounts) {
+	row := claimAmounts{
+		ClaimID:         amounts.ClaimID,
+		Name:            amounts.Name,
+		Height:          amounts.Height,
+		Bid:             amounts.Bid,
+		OwnerSupports:   amounts.OwnerSupports,
+		Tips:            amounts.Tips,
+		EffectiveAmount: amounts.EffectiveAmount(),
+	}
+	err := DB.Exec(`INSERT INTO claim_amounts VALUES ?`, &row)
+	if err != nil {
+		logrus.Error(errors.Prefix("could not save the amounts of claim "+amounts.ClaimID, err))
+	}
+}
@@ -18,4 +18,5 @@ func Start() {
 	err = DB.Exec("CREATE TABLE inputs")
 	err = DB.Exec("CREATE TABLE outputs")
 	err = DB.Exec("CREATE TABLE claims")
+	err = DB.Exec("CREATE TABLE claim_amounts")
 }
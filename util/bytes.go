@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"golang.org/x/crypto/ripemd160"
+	"io"
 )
 
 // ReverseBytes reverses a byte slice. useful for switching endian-ness
@@ -44,3 +46,39 @@ func ClaimIDFromOutpoint(txid string, nout int) (string, error) {
 
 	return hex.EncodeToString(res), nil
 }
+
+// ReadVarInt reads the MSB base-128 VARINT lbrycrd uses in its databases and undo files. See
+// https://github.com/lbryio/lbrycrd/blob/master/src/serialize.h
+func ReadVarInt(r io.ByteReader) (uint64, error) {
+	var n uint64
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, errors.Err(err)
+		}
+		if n > (^uint64(0) >> 7) {
+			return 0, errors.Err("varint is too large")
+		}
+		n = (n << 7) | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// WriteVarInt encodes n in the VARINT format read by ReadVarInt.
+func WriteVarInt(n uint64) []byte {
+	var encoded []byte
+	for {
+		b := byte(n & 0x7f)
+		if len(encoded) > 0 {
+			b |= 0x80
+		}
+		encoded = append([]byte{b}, encoded...)
+		if n <= 0x7f {
+			return encoded
+		}
+		n = (n >> 7) - 1
+	}
+}
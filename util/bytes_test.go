@@ -0,0 +1,25 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadVarInt(t *testing.T) {
+	for _, n := range []uint64{0, 1, 127, 128, 255, 16383, 16384, 1 << 32, 1<<63 - 1} {
+		encoded := WriteVarInt(n)
+		decoded, err := ReadVarInt(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != n {
+			t.Errorf("expected %d, got %d from %x", n, decoded, encoded)
+		}
+	}
+	// encodings from the comment on VARINT in lbrycrd's serialize.h
+	for n, expected := range map[uint64][]byte{127: {0x7f}, 128: {0x80, 0x00}, 255: {0x80, 0x7f}, 16383: {0xfe, 0x7f}, 16384: {0xff, 0x00}, 65535: {0x82, 0xfe, 0x7f}} {
+		if encoded := WriteVarInt(n); !bytes.Equal(encoded, expected) {
+			t.Errorf("expected %d to encode as %x, got %x", n, expected, encoded)
+		}
+	}
+}